@@ -7,6 +7,11 @@
 
 package ggpack
 
+import (
+	"errors"
+	"io"
+)
+
 var bnutPass = [...]byte{
 	0x04, 0x1f, 0x5a, 0xac, 0x5f, 0x79, 0x10, 0xaf, 0x04, 0x1d,
 	0x46, 0x3a, 0x5f, 0x08, 0xee, 0xcb, 0xb5, 0x29, 0x06, 0x2e,
@@ -420,6 +425,20 @@ var bnutPass = [...]byte{
 	0x1c, 0xfb, 0x89, 0xee, 0xa9, 0x39,
 }
 
+// EncodeBnut encodes a script for storing it as .bnut file.
+// Like in the original files the script is padded with
+// the given number of NUL bytes.
+// The script is not modified.
+func EncodeBnut(script []byte, padding int) []byte {
+	code := make([]byte, len(script)+padding)
+	copy(code, script)
+	// The encoding is its own inverse.
+	DecodeBnut(code)
+	return code
+}
+
+// DecodeBnut decodes a .bnut script in place.
+// The NUL padding at the end is not removed.
 func DecodeBnut(code []byte) {
 	cursor := len(code) & 0xff
 	for i := range code {
@@ -427,3 +446,112 @@ func DecodeBnut(code []byte) {
 		cursor = (cursor + 1) % len(bnutPass)
 	}
 }
+
+type bnutReader struct {
+	r      io.ReaderAt
+	start  int
+	length int64
+	pos    int64
+}
+
+// NewBnutReader returns a reader which decodes the BNUT script
+// of the given size in r on the fly. This is the streaming
+// counterpart of DecodeBnut. The scripts are padded with NUL bytes
+// which are not part of the decoded content. The length of the
+// content can be found by seeking to the end.
+// To decode a script stored in a ggpack container r is typically
+// the reader returned by NewEntryReader. ReadAt keeps no state
+// so it may be called in parallel if r allows it.
+func NewBnutReader(r io.ReaderAt, size int64) (ReadSeekerAt, error) {
+	br := &bnutReader{r: r, start: int(size & 0xff)}
+	if err := br.trim(size); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
+
+func (br *bnutReader) decode(p []byte, pos int64) {
+	cursor := int((int64(br.start) + pos) % int64(len(bnutPass)))
+	for i := range p {
+		p[i] ^= bnutPass[cursor]
+		if cursor++; cursor == len(bnutPass) {
+			cursor = 0
+		}
+	}
+}
+
+// trim finds the end of the content by scanning the padding
+// backwards from the end.
+func (br *bnutReader) trim(size int64) error {
+	var buf [256]byte
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if err := readFullAt(br.r, chunk, start); err != nil {
+			return err
+		}
+		br.decode(chunk, start)
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != 0 {
+				br.length = start + int64(i) + 1
+				return nil
+			}
+		}
+		end = start
+	}
+	br.length = 0
+	return nil
+}
+
+func (br *bnutReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= br.length {
+		return 0, io.EOF
+	}
+	short := false
+	if rest := br.length - off; int64(len(p)) > rest {
+		p, short = p[:rest], true
+	}
+	n, err := br.r.ReadAt(p, off)
+	br.decode(p[:n], off)
+	if n == len(p) {
+		if err = nil; short {
+			err = io.EOF
+		}
+	}
+	return n, err
+}
+
+func (br *bnutReader) Read(p []byte) (int, error) {
+	if br.pos >= br.length {
+		return 0, io.EOF
+	}
+	n, err := br.ReadAt(p, br.pos)
+	br.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (br *bnutReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += br.pos
+	case io.SeekEnd:
+		offset += br.length
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	br.pos = offset
+	return offset, nil
+}
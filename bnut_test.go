@@ -0,0 +1,80 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// randomScript returns random data without NUL bytes
+// as these mark the padding of .bnut scripts.
+func randomScript(n int, seed int64) []byte {
+	script := randomBytes(n, seed)
+	for i := range script {
+		script[i] |= 1
+	}
+	return script
+}
+
+func TestBnutRoundTrip(t *testing.T) {
+	for _, padding := range []int{0, 1, 3} {
+		for _, size := range testSizes {
+			script := randomScript(size, int64(size))
+			code := EncodeBnut(script, padding)
+			if len(code) != size+padding {
+				t.Fatalf("%d/%d: encoded length %d", size, padding, len(code))
+			}
+			DecodeBnut(code)
+			if !bytes.Equal(code[:size], script) {
+				t.Errorf("%d/%d: round trip failed", size, padding)
+			}
+			if !bytes.Equal(code[size:], make([]byte, padding)) {
+				t.Errorf("%d/%d: padding not NUL", size, padding)
+			}
+		}
+	}
+}
+
+func TestBnutReader(t *testing.T) {
+	for _, method := range testMethods {
+		for _, size := range testSizes {
+			script := randomScript(size, int64(size))
+			code := EncodeBnut(script, 1)
+			EncodeXOR(code, method)
+			er := NewEntryReader(bytes.NewReader(code), 0, int64(len(code)), method)
+			br, err := NewBnutReader(er, int64(len(code)))
+			if err != nil {
+				t.Fatalf("%s/%d: %v", method, size, err)
+			}
+			got, err := ioutil.ReadAll(br)
+			if err != nil {
+				t.Fatalf("%s/%d: reading failed: %v", method, size, err)
+			}
+			if !bytes.Equal(got, script) {
+				t.Fatalf("%s/%d: decoded script differs", method, size)
+			}
+			checkSeeks(t, br, script)
+		}
+	}
+}
+
+func TestOpenEntryBnutParallelReadAt(t *testing.T) {
+	script := randomScript(1000, 2)
+	r := writePack(t, MethodDefault, map[string][]byte{"test.bnut": script})
+	e, err := r.Lookup("test.bnut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd, err := r.OpenEntry(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParallelReadAt(t, rd, script)
+}
@@ -0,0 +1,142 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NewString returns a new string value.
+func NewString(s string) *Value {
+	return &Value{typ: StringType, str: s}
+}
+
+// NewInteger returns a new integer value.
+func NewInteger(i int64) *Value {
+	return &Value{typ: IntegerType, integer: i}
+}
+
+// NewDouble returns a new double value.
+func NewDouble(d float64) *Value {
+	return &Value{typ: DoubleType, double: d}
+}
+
+// NewArray returns a new array value with the given elements.
+// nil elements are stored as Null.
+func NewArray(elems ...*Value) *Value {
+	v := &Value{typ: ArrayType, array: make([]*Value, 0, len(elems))}
+	v.Append(elems...)
+	return v
+}
+
+// NewHash returns a new hash value with the given entries.
+// They are added with Set so later entries replace
+// earlier ones with the same key.
+func NewHash(entries ...HashEntry) *Value {
+	v := &Value{typ: HashType, hash: make(HashEntries, 0, len(entries))}
+	for _, e := range entries {
+		v.Set(e.Key, e.Value)
+	}
+	return v
+}
+
+func orNull(v *Value) *Value {
+	if v == nil {
+		return Null
+	}
+	return v
+}
+
+func (v *Value) mustBe(op string, typ ValueType) {
+	if v == nil {
+		panic(fmt.Sprintf("ggpack: %s on nil value", op))
+	}
+	if v.typ != typ {
+		panic(fmt.Sprintf("ggpack: %s on %s value", op, v.typ))
+	}
+}
+
+// Set stores x under key in the hash v. An existing entry
+// with the same key is replaced. The entries are kept sorted
+// by key as Find relies on it. nil is stored as Null.
+// Set panics if v is not a hash.
+func (v *Value) Set(key string, x *Value) {
+	v.mustBe("Set", HashType)
+	idx := sort.Search(len(v.hash), func(i int) bool {
+		return v.hash[i].Key >= key
+	})
+	if idx < len(v.hash) && v.hash[idx].Key == key {
+		v.hash[idx].Value = orNull(x)
+		return
+	}
+	v.hash = append(v.hash, HashEntry{})
+	copy(v.hash[idx+1:], v.hash[idx:])
+	v.hash[idx] = HashEntry{Key: key, Value: orNull(x)}
+}
+
+// Delete removes the entry with the given key from the hash v
+// and reports if it existed. Delete panics if v is not a hash.
+func (v *Value) Delete(key string) bool {
+	v.mustBe("Delete", HashType)
+	idx := sort.Search(len(v.hash), func(i int) bool {
+		return v.hash[i].Key >= key
+	})
+	if idx >= len(v.hash) || v.hash[idx].Key != key {
+		return false
+	}
+	v.hash = append(v.hash[:idx], v.hash[idx+1:]...)
+	return true
+}
+
+// Append appends the elements to the array v. nil elements
+// are stored as Null. Append panics if v is not an array.
+func (v *Value) Append(elems ...*Value) {
+	v.mustBe("Append", ArrayType)
+	for _, e := range elems {
+		v.array = append(v.array, orNull(e))
+	}
+}
+
+// Insert inserts the elements into the array v before index i.
+// nil elements are stored as Null. Insert panics if v is not
+// an array or i is out of range.
+func (v *Value) Insert(i int, elems ...*Value) {
+	v.mustBe("Insert", ArrayType)
+	if i < 0 || i > len(v.array) {
+		panic(fmt.Sprintf("ggpack: Insert index %d out of range [0:%d]", i, len(v.array)))
+	}
+	n := len(elems)
+	v.array = append(v.array, make([]*Value, n)...)
+	copy(v.array[i+n:], v.array[i:])
+	for j, e := range elems {
+		v.array[i+j] = orNull(e)
+	}
+}
+
+// DeepCopy returns a copy of v which shares no hashes
+// or arrays with v.
+func (v *Value) DeepCopy() *Value {
+	if v == nil || v == Null {
+		return v
+	}
+	c := *v
+	switch v.typ {
+	case HashType:
+		c.hash = make(HashEntries, len(v.hash))
+		for i, e := range v.hash {
+			c.hash[i] = HashEntry{Key: e.Key, Value: e.Value.DeepCopy()}
+		}
+	case ArrayType:
+		c.array = make([]*Value, len(v.array))
+		for i, e := range v.array {
+			c.array[i] = e.DeepCopy()
+		}
+	}
+	return &c
+}
@@ -0,0 +1,181 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"strings"
+	"testing"
+)
+
+// keys returns the keys of a hash in their order.
+func keys(v *Value) string {
+	var ks []string
+	for _, e := range v.Hash() {
+		ks = append(ks, e.Key)
+	}
+	return strings.Join(ks, ",")
+}
+
+// panics returns the message fn panics with or "" if it does not.
+func panics(fn func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg, _ = r.(string)
+		}
+	}()
+	fn()
+	return ""
+}
+
+func TestSet(t *testing.T) {
+	v := NewHash(
+		HashEntry{"c", NewInteger(3)},
+		HashEntry{"a", NewInteger(1)},
+		HashEntry{"a", NewInteger(2)})
+	if got := keys(v); got != "a,c" {
+		t.Fatalf("keys %q, want \"a,c\"", got)
+	}
+	if got := v.Find("a").Integer(); got != 2 {
+		t.Errorf("a = %d, want 2 as later entries win", got)
+	}
+
+	v.Set("b", NewString("x"))
+	v.Set("d", nil)
+	v.Set("0", NewString("y"))
+	if got := keys(v); got != "0,a,b,c,d" {
+		t.Errorf("keys %q after inserts, want \"0,a,b,c,d\"", got)
+	}
+	if d := v.Find("d"); d != Null {
+		t.Errorf("d = %v, want Null", d)
+	}
+
+	v.Set("b", NewString("replaced"))
+	if got := keys(v); got != "0,a,b,c,d" {
+		t.Errorf("keys %q after replace, want \"0,a,b,c,d\"", got)
+	}
+	if got := v.Find("b").String(); got != "replaced" {
+		t.Errorf("b = %q, want \"replaced\"", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	v := NewHash(
+		HashEntry{"a", Null},
+		HashEntry{"b", Null},
+		HashEntry{"c", Null})
+	for _, tc := range []struct {
+		key  string
+		ok   bool
+		keys string
+	}{
+		{"b", true, "a,c"},
+		{"b", false, "a,c"},
+		{"x", false, "a,c"},
+		{"", false, "a,c"},
+		{"c", true, "a"},
+		{"a", true, ""},
+		{"a", false, ""},
+	} {
+		if ok := v.Delete(tc.key); ok != tc.ok {
+			t.Errorf("Delete(%q) = %t, want %t", tc.key, ok, tc.ok)
+		}
+		if got := keys(v); got != tc.keys {
+			t.Errorf("keys %q after deleting %q, want %q", got, tc.key, tc.keys)
+		}
+	}
+}
+
+func TestAppendInsert(t *testing.T) {
+	v := NewArray(NewInteger(1), nil)
+	v.Append(NewInteger(4), nil)
+	v.Insert(0, NewInteger(0))
+	v.Insert(2, NewInteger(2), NewInteger(3))
+	v.Insert(len(v.Array()), NewInteger(5))
+	v.Insert(1)
+	if got, want := jsonString(t, v), "[0,1,2,3,null,4,null,5]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for i, e := range v.Array() {
+		if e == nil {
+			t.Errorf("element %d is nil, want Null", i)
+		}
+	}
+
+	for _, i := range []int{-1, len(v.Array()) + 1} {
+		msg := panics(func() { v.Insert(i, Null) })
+		if !strings.Contains(msg, "out of range") {
+			t.Errorf("Insert(%d): panic %q, want out of range", i, msg)
+		}
+	}
+	if n := len(v.Array()); n != 8 {
+		t.Errorf("%d elements after failed inserts, want 8", n)
+	}
+}
+
+func TestBuilderTypePanics(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   func()
+		want string
+	}{
+		{"Set on array", func() { NewArray().Set("a", nil) }, "ggpack: Set on array value"},
+		{"Delete on string", func() { NewString("x").Delete("a") }, "ggpack: Delete on string value"},
+		{"Append on hash", func() { NewHash().Append(nil) }, "ggpack: Append on hash value"},
+		{"Insert on nil", func() { (*Value)(nil).Insert(0) }, "ggpack: Insert on nil value"},
+	} {
+		if got := panics(tc.fn); got != tc.want {
+			t.Errorf("%s: panic %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	orig := jsonValue(t, `{"a": [1, {"b": "x"}], "c": {"d": [2.5]}, "n": null}`)
+	before := jsonString(t, orig)
+
+	c := orig.DeepCopy()
+	if got := jsonString(t, c); got != before {
+		t.Fatalf("copy %s, want %s", got, before)
+	}
+
+	// No hash or array may be shared.
+	var walk func(a, b *Value, path string)
+	walk = func(a, b *Value, path string) {
+		if a.Type() == HashType || a.Type() == ArrayType {
+			if a == b {
+				t.Errorf("%s: value is shared", path)
+			}
+		}
+		switch a.Type() {
+		case HashType:
+			for i, e := range a.Hash() {
+				walk(e.Value, b.Hash()[i].Value, keyPath(path, e.Key))
+			}
+		case ArrayType:
+			for i, e := range a.Array() {
+				walk(e, b.Array()[i], indexPath(path, i))
+			}
+		}
+	}
+	walk(orig, c, "$")
+
+	c.Set("a", NewString("changed"))
+	c.Find("c").Find("d").Append(NewInteger(3))
+	c.Find("c").Delete("d")
+	c.Set("new", Null)
+	if got := jsonString(t, orig); got != before {
+		t.Errorf("original changed to %s, want %s", got, before)
+	}
+
+	if (*Value)(nil).DeepCopy() != nil {
+		t.Error("copy of nil is not nil")
+	}
+	if Null.DeepCopy() != Null {
+		t.Error("copy of Null is not Null")
+	}
+}
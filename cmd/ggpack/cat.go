@@ -0,0 +1,43 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+)
+
+var catCmd = &command{
+	name:    "cat",
+	args:    "<ggpack-glob> <name>...",
+	descr:   "write decoded files to standard output",
+	minArgs: 2,
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		stdout := bufio.NewWriter(os.Stdout)
+		for _, name := range flags.Args()[1:] {
+			in, err := index.Open(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(stdout, in)
+			in.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return stdout.Flush()
+	},
+}
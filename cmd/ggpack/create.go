@@ -0,0 +1,78 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+// packDir adds the regular files below root to writer.
+// skip is the container being written which must not be packed
+// into itself if it is placed inside of root.
+func packDir(writer *ggpack.Writer, root string, skip os.FileInfo) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || os.SameFile(info, skip) {
+			return err
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writer.WriteFile(filepath.ToSlash(name), data)
+	})
+}
+
+var createCmd = &command{
+	name:    "create",
+	args:    "<directory> <ggpack>",
+	descr:   "create a container from the files of a directory",
+	minArgs: 2,
+	run: func(flags *flag.FlagSet) error {
+		return create(flags.Arg(0), flags.Arg(1))
+	},
+}
+
+func create(root, fname string) error {
+
+	m := ggpack.MethodDefault
+	if method >= 0 {
+		m = ggpack.Method(method)
+	}
+
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+
+	self, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer := ggpack.Writer{Writer: file, Method: m}
+
+	if err := packDir(&writer, root, self); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
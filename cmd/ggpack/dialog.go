@@ -0,0 +1,100 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/s-l-teichmann/ggpack/yack"
+)
+
+var dialogCmd = &command{
+	name:    "dialog",
+	args:    "<ggpack-glob> <name.yack|name.byack>",
+	descr:   "print a dialog as tree or JSON",
+	minArgs: 2,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpJSON, "json", false, "write the dialog as JSON")
+	},
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		e, err := index.Lookup(flags.Arg(1))
+		if err != nil {
+			return err
+		}
+		src, err := readEntry(index, e)
+		if err != nil {
+			return err
+		}
+		dialog, perr := parseDialog(e.Name, src)
+		if dialog == nil {
+			return perr
+		}
+
+		if dumpJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(dialog); err != nil {
+				return err
+			}
+		} else if err := printDialog(dialog); err != nil {
+			return err
+		}
+		return syntaxErrors(e.Name, perr)
+	},
+}
+
+// isDialog reports if the file is a yack dialog by its extension.
+func isDialog(name string) bool {
+	ext := path.Ext(name)
+	return strings.EqualFold(ext, ".yack") || strings.EqualFold(ext, ".byack")
+}
+
+// isText reports if src looks like yack source.
+func isText(src []byte) bool {
+	return utf8.Valid(src) && bytes.IndexByte(src, 0) < 0
+}
+
+// parseDialog parses a yack dialog. .byack files are parsed like
+// .yack files. As no encoding of compiled dialogs is known binary
+// .byack content is refused. The dialog is nil if the source cannot
+// be parsed at all.
+func parseDialog(name string, src []byte) (*yack.Dialog, error) {
+	if strings.EqualFold(path.Ext(name), ".byack") && !isText(src) {
+		return nil, fmt.Errorf("%s: no yack source, compiled dialogs cannot be decoded", name)
+	}
+	return yack.Parse(src)
+}
+
+func printDialog(dialog *yack.Dialog) error {
+	stdout := bufio.NewWriter(os.Stdout)
+	for i, l := range dialog.Labels {
+		if i > 0 {
+			stdout.WriteByte('\n')
+		}
+		fmt.Fprintf(stdout, ":%s (%s)\n", l.Name, l.Pos)
+		for _, s := range l.Stmts {
+			fmt.Fprintf(stdout, "  %-7s %s\n", s.Pos, s)
+		}
+	}
+	return stdout.Flush()
+}
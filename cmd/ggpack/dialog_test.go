@@ -0,0 +1,30 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import "testing"
+
+func TestParseDialogByack(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"Ray.yack", ":main\nray: \"Hi\"\n", true},
+		{"Ray.byack", ":main\nray: \"Hi\"\n", true},
+		{"Ray.BYACK", ":main\nray: \"Hi\"\n", true},
+		{"Ray.byack", "\x01\x00\x02", false},
+		{"Ray.byack", "\xff\xfe", false},
+	} {
+		dialog, err := parseDialog(tc.name, []byte(tc.src))
+		if got := dialog != nil && err == nil; got != tc.ok {
+			t.Errorf("%s %q: dialog = %v, err = %v, want ok = %t",
+				tc.name, tc.src, dialog, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/s-l-teichmann/ggpack"
+	"github.com/s-l-teichmann/ggpack/yack"
+)
+
+var graphFormat = "dot"
+
+var dialogGraphCmd = &command{
+	name:    "dialog-graph",
+	args:    "<ggpack-glob> [pattern]...",
+	descr:   "export the flow of dialogs as DOT or Mermaid graph (all without patterns)",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.StringVar(&graphFormat, "format", "dot", "graph format 'dot' or 'mermaid'")
+		flags.StringVar(&dir, "dir", "", "directory to write one graph per dialog to (default standard output)")
+		regexpFlag(flags)
+	},
+	run: func(flags *flag.FlagSet) error {
+		var write func(io.Writer, string, *yack.Flow) error
+		var ext string
+		switch graphFormat {
+		case "dot":
+			write, ext = writeDOT, ".dot"
+		case "mermaid":
+			write, ext = writeMermaid, ".mmd"
+		default:
+			return fmt.Errorf("unknown graph format %q", graphFormat)
+		}
+
+		match, err := newMatcher(flags.Args()[1:], useRegexp)
+		if err != nil {
+			return err
+		}
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		found := false
+		stdout := bufio.NewWriter(os.Stdout)
+		if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+			if !match(e.Name) || !isDialog(e.Name) {
+				return nil
+			}
+			found = true
+			flow := loadFlow(index, e)
+			if flow == nil {
+				return nil
+			}
+			title := strings.TrimSuffix(path.Base(e.Name), path.Ext(e.Name))
+
+			if dir == "" {
+				return write(stdout, title, flow)
+			}
+			fname, err := outputPath(dir, e.Name)
+			if err != nil {
+				return err
+			}
+			fname = strings.TrimSuffix(fname, filepath.Ext(fname)) + ext
+			if err := os.MkdirAll(filepath.Dir(fname), 0777); err != nil {
+				return err
+			}
+			out, err := os.Create(fname)
+			if err != nil {
+				return err
+			}
+			w := bufio.NewWriter(out)
+			if err := write(w, title, flow); err != nil {
+				out.Close()
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}); err != nil {
+			return err
+		}
+		if err := stdout.Flush(); err != nil {
+			return err
+		}
+		if !found {
+			return errNoMatch
+		}
+		return nil
+	},
+}
+
+// loadFlow reads and parses a dialog and analyses its flow.
+// Problems are logged as warnings so that one broken dialog
+// does not stop the export of the others. It returns nil if
+// the dialog cannot be used at all.
+func loadFlow(index *packs, e *ggpack.OverlayEntry) *yack.Flow {
+	src, err := readEntry(index, e)
+	if err != nil {
+		log.Printf("warning: %s: %v\n", e.Name, err)
+		return nil
+	}
+	dialog, err := parseDialog(e.Name, src)
+	if dialog == nil {
+		log.Printf("warning: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		log.Printf("warning: %v\n", syntaxErrors(e.Name, err))
+	}
+	return yack.NewFlow(dialog)
+}
+
+// graphNodes names the nodes of a dialog flow graph.
+// Labels are "l_<name>", choices "c_<label>_<stmt>",
+// undefined labels "u_<name>" and the end of the dialog "exit".
+type graphNodes struct {
+	flow      *yack.Flow
+	undefined []string
+}
+
+func (g *graphNodes) target(name string) string {
+	if g.flow.Dialog.Label(name) != nil {
+		return "l_" + name
+	}
+	if name == yack.ExitLabel {
+		return "exit"
+	}
+	for _, u := range g.undefined {
+		if u == name {
+			return "u_" + name
+		}
+	}
+	g.undefined = append(g.undefined, name)
+	return "u_" + name
+}
+
+// edge is an edge of the flow graph.
+type edge struct {
+	from, to string
+	dashed   bool
+	text     string
+}
+
+// edges returns the edges of the flow and calls choice
+// for every choice.
+func (g *graphNodes) edges(choice func(id string, s *yack.Stmt, c *yack.Choice)) []edge {
+	var edges []edge
+	for i, l := range g.flow.Dialog.Labels {
+		from := "l_" + l.Name
+		for j, s := range l.Stmts {
+			target, ok := s.Target()
+			if !ok {
+				continue
+			}
+			to := g.target(target)
+			cond := len(s.Conditions) > 0
+			switch c := s.Cmd.(type) {
+			case *yack.Choice:
+				id := fmt.Sprintf("c_%d_%d", i, j)
+				choice(id, s, c)
+				edges = append(edges,
+					edge{from: from, to: id, dashed: cond},
+					edge{from: id, to: to})
+			case *yack.Override:
+				edges = append(edges, edge{from: from, to: to, dashed: true, text: "override"})
+			default:
+				edges = append(edges, edge{from: from, to: to, dashed: cond})
+			}
+		}
+	}
+	return edges
+}
+
+// choiceText returns the text shown for a choice node.
+func choiceText(s *yack.Stmt, c *yack.Choice) string {
+	text := c.Text
+	if c.Code != "" {
+		text = "$" + c.Code
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d: %s", c.Number, text)
+	for _, cond := range s.Conditions {
+		b.WriteString(" [" + cond.String() + "]")
+	}
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func writeDOT(w io.Writer, title string, flow *yack.Flow) error {
+	g := graphNodes{flow: flow}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", dotQuote(title))
+	out.WriteString("  node [shape=box];\n")
+
+	for _, l := range flow.Dialog.Labels {
+		attrs := ""
+		switch {
+		case flow.IsUnreachable(l):
+			attrs = `, style="dashed,filled", fillcolor=lightgray, xlabel="unreachable"`
+		case l == flow.Start:
+			attrs = ", penwidth=2"
+		}
+		fmt.Fprintf(out, "  l_%s [label=%s%s];\n", l.Name, dotQuote(l.Name), attrs)
+	}
+
+	edges := g.edges(func(id string, s *yack.Stmt, c *yack.Choice) {
+		attrs := ""
+		if flow.IsDeadEnd(s) {
+			attrs = `, color=red, xlabel="dead end"`
+		}
+		fmt.Fprintf(out, "  %s [shape=ellipse, label=%s%s];\n", id, dotQuote(choiceText(s, c)), attrs)
+	})
+
+	for _, name := range g.undefined {
+		fmt.Fprintf(out, "  u_%s [label=%s, color=red, style=dashed, xlabel=\"undefined\"];\n", name, dotQuote(name))
+	}
+	for _, e := range edges {
+		if e.to == "exit" {
+			out.WriteString("  exit [shape=doublecircle, label=\"exit\"];\n")
+			break
+		}
+	}
+
+	for _, e := range edges {
+		var attrs []string
+		if e.dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.text != "" {
+			attrs = append(attrs, "label="+dotQuote(e.text))
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(out, "  %s -> %s [%s];\n", e.from, e.to, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(out, "  %s -> %s;\n", e.from, e.to)
+		}
+	}
+	out.WriteString("}\n")
+	return out.Flush()
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+}
+
+func writeMermaid(w io.Writer, title string, flow *yack.Flow) error {
+	g := graphNodes{flow: flow}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "---\ntitle: %s\n---\n", title)
+	out.WriteString("flowchart TD\n")
+
+	var unreachable, deadEnds []string
+	for _, l := range flow.Dialog.Labels {
+		id := "l_" + l.Name
+		fmt.Fprintf(out, "  %s[%s]\n", id, mermaidQuote(l.Name))
+		if flow.IsUnreachable(l) {
+			unreachable = append(unreachable, id)
+		}
+	}
+
+	edges := g.edges(func(id string, s *yack.Stmt, c *yack.Choice) {
+		fmt.Fprintf(out, "  %s([%s])\n", id, mermaidQuote(choiceText(s, c)))
+		if flow.IsDeadEnd(s) {
+			deadEnds = append(deadEnds, id)
+		}
+	})
+
+	var undefined []string
+	for _, name := range g.undefined {
+		id := "u_" + name
+		fmt.Fprintf(out, "  %s[%s]\n", id, mermaidQuote(name+" (undefined)"))
+		undefined = append(undefined, id)
+	}
+	for _, e := range edges {
+		if e.to == "exit" {
+			out.WriteString("  exit(((exit)))\n")
+			break
+		}
+	}
+
+	for _, e := range edges {
+		arrow := "-->"
+		if e.dashed {
+			arrow = "-.->"
+		}
+		if e.text != "" {
+			arrow += "|" + e.text + "|"
+		}
+		fmt.Fprintf(out, "  %s %s %s\n", e.from, arrow, e.to)
+	}
+
+	classes := []struct {
+		name, style string
+		ids         []string
+	}{
+		{"unreachable", "fill:#ddd,stroke-dasharray:5 5", unreachable},
+		{"deadend", "stroke:#f00,stroke-width:2px", deadEnds},
+		{"undefined", "stroke:#f00,stroke-dasharray:5 5", undefined},
+	}
+	for _, c := range classes {
+		if len(c.ids) > 0 {
+			fmt.Fprintf(out, "  classDef %s %s\n", c.name, c.style)
+			fmt.Fprintf(out, "  class %s %s\n", strings.Join(c.ids, ","), c.name)
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,85 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/s-l-teichmann/ggpack/yack"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// graphDialog exercises all node and edge kinds of the graphs.
+const graphDialog = `:intro
+-> start
+:start
+1 "Who are you?" -> who [once]
+2 $g.names["ray"] -> ray
+3 "Back door" -> nowhere
+4 "Bye." -> exit
+override who
+:who
+reyes: "I'm \"Reyes\"."
+-> start [!g.met]
+:ray
+ray: "Nothing to say."
+`
+
+func TestGraphWriters(t *testing.T) {
+	dialog, err := yack.Parse([]byte(graphDialog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow := yack.NewFlow(dialog)
+
+	for _, tc := range []struct {
+		golden string
+		write  func(io.Writer, string, *yack.Flow) error
+	}{
+		{"graph.dot", writeDOT},
+		{"graph.mmd", writeMermaid},
+	} {
+		var buf bytes.Buffer
+		if err := tc.write(&buf, "Reyes", flow); err != nil {
+			t.Fatalf("%s: %v", tc.golden, err)
+		}
+		golden := filepath.Join("testdata", tc.golden)
+		if *update {
+			if err := os.WriteFile(golden, buf.Bytes(), 0666); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.golden, buf.Bytes(), want)
+		}
+	}
+}
+
+func TestParseDialogBroken(t *testing.T) {
+	dialog, err := parseDialog("Broken.yack", []byte(":main\n  [once]\n[]\nray: \"ok\"\n"))
+	if dialog == nil {
+		t.Fatalf("no dialog: %v", err)
+	}
+	if err == nil {
+		t.Error("no syntax errors")
+	}
+	if n := len(dialog.Labels); n != 1 || len(dialog.Labels[0].Stmts) != 1 {
+		t.Errorf("got %d labels, want one with one statement", n)
+	}
+}
@@ -0,0 +1,215 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var diffCmd = &command{
+	name:    "diff",
+	args:    "<old-ggpack-glob> <new-ggpack-glob>",
+	descr:   "compare the files of two containers",
+	minArgs: 2,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpJSON, "json", false, "write the differences as JSON")
+	},
+	run: func(flags *flag.FlagSet) error {
+		older, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer older.Close()
+		newer, err := loadPacks(flags.Args()[1:2])
+		if err != nil {
+			return err
+		}
+		defer newer.Close()
+
+		diffs, err := diffPacks(older, newer)
+		if err != nil {
+			return err
+		}
+		if dumpJSON {
+			if diffs == nil {
+				diffs = []fileDiff{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(diffs)
+		} else {
+			err = writeDiffs(os.Stdout, diffs)
+		}
+		if err != nil {
+			return err
+		}
+		if len(diffs) > 0 {
+			return errDiffers
+		}
+		return nil
+	},
+}
+
+type fileDiff struct {
+	Name    string      `json:"name"`
+	Change  string      `json:"change"`
+	OldSize int64       `json:"oldSize"`
+	NewSize int64       `json:"newSize"`
+	OldHash string      `json:"oldHash,omitempty"`
+	NewHash string      `json:"newHash,omitempty"`
+	Values  []valueDiff `json:"values,omitempty"`
+}
+
+type valueDiff struct {
+	Change string        `json:"change"`
+	Path   string        `json:"path"`
+	Old    *ggpack.Value `json:"old,omitempty"`
+	New    *ggpack.Value `json:"new,omitempty"`
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// decodedSize returns the size of the decoded content of the file
+// without reading all of it. For .bnut scripts it excludes the padding.
+func decodedSize(index *packs, e *ggpack.OverlayEntry) (int64, error) {
+	in, err := index.OpenEntry(e)
+	if err != nil {
+		return 0, err
+	}
+	return in.Seek(0, io.SeekEnd)
+}
+
+// diffPacks compares the files of two containers. Files found
+// in both with a different content are decoded as dictionaries
+// if possible to compare their values, too. All sizes are the
+// sizes of the decoded contents.
+func diffPacks(older, newer *packs) ([]fileDiff, error) {
+
+	var diffs []fileDiff
+
+	if err := handleFiles(older, func(oe *ggpack.OverlayEntry) error {
+		ne, err := newer.Lookup(oe.Name)
+		if err != nil {
+			size, err := decodedSize(older, oe)
+			if err != nil {
+				return fmt.Errorf("%s: %v", oe.Name, err)
+			}
+			diffs = append(diffs, fileDiff{
+				Name:    oe.Name,
+				Change:  ggpack.ChangeRemoved.String(),
+				OldSize: size,
+			})
+			return nil
+		}
+		oldData, err := readEntry(older, oe)
+		if err != nil {
+			return fmt.Errorf("%s: %v", oe.Name, err)
+		}
+		newData, err := readEntry(newer, ne)
+		if err != nil {
+			return fmt.Errorf("%s: %v", ne.Name, err)
+		}
+		if bytes.Equal(oldData, newData) {
+			return nil
+		}
+		fd := fileDiff{
+			Name:    oe.Name,
+			Change:  ggpack.ChangeModified.String(),
+			OldSize: int64(len(oldData)),
+			NewSize: int64(len(newData)),
+			OldHash: sha256Hex(oldData),
+			NewHash: sha256Hex(newData),
+		}
+		if oldDict, err := ggpack.DecodeDict(oldData); err == nil {
+			if newDict, err := ggpack.DecodeDict(newData); err == nil {
+				for _, c := range ggpack.Diff(oldDict, newDict) {
+					fd.Values = append(fd.Values, valueDiff{
+						Change: c.Kind.String(),
+						Path:   "$" + pathSuffix(c.Path),
+						Old:    c.Old,
+						New:    c.New,
+					})
+				}
+			}
+		}
+		diffs = append(diffs, fd)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := handleFiles(newer, func(ne *ggpack.OverlayEntry) error {
+		if _, err := older.Lookup(ne.Name); err == nil {
+			return nil
+		}
+		size, err := decodedSize(newer, ne)
+		if err != nil {
+			return fmt.Errorf("%s: %v", ne.Name, err)
+		}
+		diffs = append(diffs, fileDiff{
+			Name:    ne.Name,
+			Change:  ggpack.ChangeAdded.String(),
+			NewSize: size,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return diffs, nil
+}
+
+func writeDiffs(w io.Writer, diffs []fileDiff) error {
+
+	compact := func(v *ggpack.Value) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("<%v>", err)
+		}
+		return string(data)
+	}
+
+	out := bufio.NewWriter(w)
+	for i := range diffs {
+		fd := &diffs[i]
+		switch fd.Change {
+		case ggpack.ChangeAdded.String():
+			fmt.Fprintf(out, "+ %s (%d bytes)\n", fd.Name, fd.NewSize)
+		case ggpack.ChangeRemoved.String():
+			fmt.Fprintf(out, "- %s (%d bytes)\n", fd.Name, fd.OldSize)
+		default:
+			fmt.Fprintf(out, "~ %s (%d -> %d bytes, sha256 %.12s -> %.12s)\n",
+				fd.Name, fd.OldSize, fd.NewSize, fd.OldHash, fd.NewHash)
+		}
+		for _, vd := range fd.Values {
+			switch vd.Change {
+			case ggpack.ChangeAdded.String():
+				fmt.Fprintf(out, "    + %s: %s\n", vd.Path, compact(vd.New))
+			case ggpack.ChangeRemoved.String():
+				fmt.Fprintf(out, "    - %s: %s\n", vd.Path, compact(vd.Old))
+			default:
+				fmt.Fprintf(out, "    ~ %s: %s -> %s\n",
+					vd.Path, compact(vd.Old), compact(vd.New))
+			}
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,255 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var assetKinds = map[string]string{
+	".png":   "images",
+	".ktxbz": "images",
+	".bnut":  "scripts",
+	".nut":   "scripts",
+	".yack":  "scripts",
+	".byack": "scripts",
+	".ogg":   "audio",
+	".wav":   "audio",
+	".wimpy": "rooms",
+	".json":  "data",
+	".tsv":   "data",
+	".txt":   "data",
+	".fnt":   "fonts",
+}
+
+// groupDir returns the directory to group the named file into.
+func groupDir(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch group {
+	case "ext":
+		if ext == "" {
+			return "noext"
+		}
+		return ext[1:]
+	case "kind":
+		if kind := assetKinds[ext]; kind != "" {
+			return kind
+		}
+		return "misc"
+	default:
+		return ""
+	}
+}
+
+// outputPath returns the path below root to extract the named file to.
+// Names which would escape root are refused.
+func outputPath(root, name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	if slashed == "" || path.IsAbs(slashed) ||
+		filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", errors.New("refusing to extract absolute path")
+	}
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", errors.New("refusing to extract outside of output directory")
+		}
+	}
+	return filepath.Join(root, groupDir(name), filepath.FromSlash(slashed)), nil
+}
+
+var extractCmd = &command{
+	name:    "extract",
+	args:    "<ggpack-glob> [pattern]...",
+	descr:   "extract files matching the patterns (all without patterns)",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.StringVar(&dir, "dir", ".", "directory to extract files to")
+		flags.IntVar(&workers, "j", 1, "number of files to extract concurrently")
+		flags.BoolVar(&progress, "progress", false, "report progress of extraction")
+		flags.StringVar(&group, "group", "", "group extracted files by 'ext' or 'kind'")
+		flags.BoolVar(&dumpJSON, "json", false, "dump the files as JSON dictionaries to standard output")
+		regexpFlag(flags)
+	},
+	run: func(flags *flag.FlagSet) error {
+		match, err := newMatcher(flags.Args()[1:], useRegexp)
+		if err != nil {
+			return err
+		}
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+		return extractMatching(index, match)
+	},
+}
+
+// extractMatching extracts the files of which the names match.
+// If dumpJSON is set they are decoded as dictionaries and written
+// as JSON to stdout instead.
+func extractMatching(index *packs, match func(string) bool) error {
+
+	if !dumpJSON {
+		var entries []*ggpack.OverlayEntry
+		if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+			if match(e.Name) {
+				entries = append(entries, e)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		return extractAll(index, entries)
+	}
+
+	return handleFiles(index, func(e *ggpack.OverlayEntry) error {
+		if !match(e.Name) {
+			return nil
+		}
+		value, err := decodeEntry(index, e)
+		if err != nil {
+			return err
+		}
+		return writeJSON(value)
+	})
+}
+
+func extract(index *packs, e *ggpack.OverlayEntry) error {
+	fname, err := outputPath(dir, e.Name)
+	if err != nil {
+		return err
+	}
+	in, err := index.OpenEntry(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fname), 0777); err != nil {
+		return err
+	}
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+type extractProgress struct {
+	files, bytes           int64
+	totalFiles, totalBytes int64
+}
+
+func (ep *extractProgress) done(e *ggpack.Entry) {
+	atomic.AddInt64(&ep.files, 1)
+	atomic.AddInt64(&ep.bytes, e.Size)
+}
+
+func (ep *extractProgress) report() {
+	fmt.Fprintf(os.Stderr, "\r%d/%d files, %d/%d bytes",
+		atomic.LoadInt64(&ep.files), ep.totalFiles,
+		atomic.LoadInt64(&ep.bytes), ep.totalBytes)
+}
+
+// extractAll extracts the entries with a pool of workers.
+// If extracting fails the error of the first failed entry
+// in the order of the entries is returned.
+func extractAll(index *packs, entries []*ggpack.OverlayEntry) error {
+
+	switch group {
+	case "", "ext", "kind":
+	default:
+		return fmt.Errorf("unknown grouping: %q", group)
+	}
+
+	ep := extractProgress{totalFiles: int64(len(entries))}
+	for _, e := range entries {
+		ep.totalBytes += e.Size
+	}
+
+	if progress {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		stop := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					ep.report()
+				case <-stop:
+					return
+				}
+			}
+		}()
+		defer func() {
+			ticker.Stop()
+			close(stop)
+			ep.report()
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	n := workers
+	if n < 1 {
+		n = 1
+	}
+
+	var (
+		errs   = make([]error, len(entries))
+		failed int32
+		jobs   = make(chan int)
+		wg     sync.WaitGroup
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				e := entries[j]
+				if err := extract(index, e); err != nil {
+					errs[j] = fmt.Errorf("%s: %v", e.Name, err)
+					atomic.StoreInt32(&failed, 1)
+					continue
+				}
+				ep.done(&e.Entry)
+			}
+		}()
+	}
+
+	// As the jobs are started in order all entries before
+	// a failed one are processed when stopping.
+	for j := range entries {
+		if atomic.LoadInt32(&failed) != 0 {
+			break
+		}
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var findCmd = &command{
+	name:    "find",
+	args:    "<ggpack-glob> <pattern>...",
+	descr:   "find files by glob or regular expression",
+	minArgs: 2,
+	flags: func(flags *flag.FlagSet) {
+		regexpFlag(flags)
+	},
+	run: func(flags *flag.FlagSet) error {
+		match, err := newMatcher(flags.Args()[1:], useRegexp)
+		if err != nil {
+			return err
+		}
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+		found := false
+		stdout := bufio.NewWriter(os.Stdout)
+		if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+			if !match(e.Name) {
+				return nil
+			}
+			found = true
+			_, err := fmt.Fprintln(stdout, e.Name)
+			return err
+		}); err != nil {
+			return err
+		}
+		if err := stdout.Flush(); err != nil {
+			return err
+		}
+		if !found {
+			return errNoMatch
+		}
+		return nil
+	},
+}
@@ -0,0 +1,59 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func printInfo(fname string) error {
+
+	index, err := loadIndex(fname)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	info, err := index.Info()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(tw, "file:\t%s\n", fname)
+	fmt.Fprintf(tw, "method:\t%s\n", info.Method)
+	fmt.Fprintf(tw, "directory offset:\t%d\n", info.DirectoryOffset)
+	fmt.Fprintf(tw, "directory size:\t%d\n", info.DirectorySize)
+	fmt.Fprintf(tw, "entries:\t%d\n", info.Entries)
+	fmt.Fprintf(tw, "strings:\t%d\n", info.Strings)
+	fmt.Fprintf(tw, "string table size:\t%d\n", info.StringTableSize)
+	fmt.Fprintf(tw, "payload size:\t%d\n", info.PayloadSize)
+	return tw.Flush()
+}
+
+var infoCmd = &command{
+	name:    "info",
+	args:    "<ggpack-glob>...",
+	descr:   "print statistics about containers",
+	minArgs: 1,
+	run: func(flags *flag.FlagSet) error {
+		fnames, err := expandPacks(flags.Args())
+		if err != nil {
+			return err
+		}
+		for _, arg := range fnames {
+			if err := printInfo(arg); err != nil {
+				return fmt.Errorf("%s: %v", arg, err)
+			}
+		}
+		return nil
+	},
+}
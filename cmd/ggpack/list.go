@@ -0,0 +1,116 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var (
+	sortBy     = ""
+	humanSizes = false
+	longFormat = false
+)
+
+var listCmd = &command{
+	name:    "list",
+	args:    "<ggpack-glob>...",
+	descr:   "list the files stored in containers as one namespace",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.StringVar(&sortBy, "sort", "", "sort by 'name', 'size' or 'offset'")
+		flags.BoolVar(&humanSizes, "human", false, "print sizes in human readable form")
+		flags.BoolVar(&longFormat, "long", false, "print offsets and the containers, too")
+		flags.BoolVar(&dumpJSON, "json", false, "dump the index as JSON")
+	},
+	run: func(flags *flag.FlagSet) error {
+		switch sortBy {
+		case "", "name", "size", "offset":
+		default:
+			return fmt.Errorf("unknown sort order: %q", sortBy)
+		}
+		index, err := loadPacks(flags.Args())
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+		return list(index)
+	},
+}
+
+func humanSize(size int64) string {
+	const units = "KMGTPE"
+	if size < 1024 {
+		return fmt.Sprintf("%d", size)
+	}
+	f, i := float64(size)/1024, 0
+	for ; f >= 1024 && i < len(units)-1; i++ {
+		f /= 1024
+	}
+	return fmt.Sprintf("%.1f%c", f, units[i])
+}
+
+func list(index *packs) error {
+
+	if dumpJSON {
+		for _, f := range index.files {
+			if err := writeJSON(f.Entries()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var entries []*ggpack.OverlayEntry
+	if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	switch sortBy {
+	case "name":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+	case "size":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Size < entries[j].Size
+		})
+	case "offset":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Offset < entries[j].Offset
+		})
+	}
+
+	stdout := bufio.NewWriter(os.Stdout)
+	for _, e := range entries {
+		size := fmt.Sprintf("%d", e.Size)
+		if humanSizes {
+			size = humanSize(e.Size)
+		}
+		var err error
+		if longFormat {
+			_, err = fmt.Fprintf(stdout, "%s\t%d\t%s\t%s\n",
+				e.Name, e.Offset, size, index.origin(e))
+		} else {
+			_, err = fmt.Fprintf(stdout, "%s\t%s\n", e.Name, size)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return stdout.Flush()
+}
@@ -8,17 +8,14 @@
 package main
 
 import (
-	"bufio"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
 
 	"github.com/s-l-teichmann/ggpack"
 )
@@ -26,127 +23,323 @@ import (
 var (
 	extractFiles = ""
 	dir          = "."
+	dumpJSON     = false
+	method       = -1
+	workers      = 1
+	progress     = false
+	group        = ""
 )
 
+// Exit codes of the commands.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errNoMatch is returned by commands which found nothing
+// to report and errDiffers by diff and verify if the contents differ.
+// They lead to exitError without a message.
+var (
+	errNoMatch = errors.New("no match")
+	errDiffers = errors.New("containers differ")
+)
+
+type command struct {
+	name    string
+	args    string
+	descr   string
+	minArgs int
+	flags   func(*flag.FlagSet)
+	run     func(*flag.FlagSet) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		listCmd,
+		extractCmd,
+		catCmd,
+		infoCmd,
+		treeCmd,
+		findCmd,
+		queryCmd,
+		diffCmd,
+		manifestCmd,
+		verifyCmd,
+		scriptASTCmd,
+		xrefCmd,
+		dialogCmd,
+		dialogGraphCmd,
+		createCmd,
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func methodFlag(flags *flag.FlagSet) {
+	flags.IntVar(&method, "method", -1,
+		"force encoding method (0-3), detect if negative")
+}
+
+func (cmd *command) main(args []string) int {
+
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	methodFlag(flags)
+	if cmd.flags != nil {
+		cmd.flags(flags)
+	}
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: %s %s [options] %s\n\n%s\n\nOptions:\n",
+			os.Args[0], cmd.name, cmd.args, cmd.descr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if flags.NArg() < cmd.minArgs {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if err := cmd.run(flags); err != nil {
+		if err != errNoMatch && err != errDiffers {
+			log.Printf("error: %s: %v\n", cmd.name, err)
+		}
+		return exitError
+	}
+	return exitOK
+}
+
 func handleFiles(
-	reader *ggpack.Reader,
-	fn func(name string, ofs, size int64) error,
+	index *packs,
+	fn func(*ggpack.OverlayEntry) error,
 ) error {
 
-	files := reader.Entries().Find("files")
-	if files == nil || files.Type() != ggpack.ArrayType {
-		return errors.New("no files found")
-	}
+	files := index.Files()
 
-	fs := files.Array()
-	for _, f := range fs {
-		if f.Type() != ggpack.HashType {
-			continue
+	for i := range files {
+		if err := fn(&files[i]); err != nil {
+			return err
 		}
-		name := f.Find("filename")
-		ofs := f.Find("offset")
-		size := f.Find("size")
-		if name != nil && ofs != nil && size != nil &&
-			name.Type() == ggpack.StringType &&
-			ofs.Type() == ggpack.IntegerType &&
-			size.Type() == ggpack.IntegerType {
-			if err := fn(name.String(), ofs.Integer(), size.Integer()); err != nil {
+	}
+
+	return nil
+}
+
+// readEntry returns the decoded content of the file.
+func readEntry(index *packs, e *ggpack.OverlayEntry) ([]byte, error) {
+	in, err := index.OpenEntry(e)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(in)
+}
+
+// decodeEntry decodes the content of the file as dictionary.
+func decodeEntry(index *packs, e *ggpack.OverlayEntry) (*ggpack.Value, error) {
+	buf, err := readEntry(index, e)
+	if err != nil {
+		return nil, err
+	}
+	value, err := ggpack.DecodeDict(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", e.Name, err)
+	}
+	return value, nil
+}
+
+func loadIndex(fname string) (*ggpack.File, error) {
+
+	file, err := ggpack.OpenFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if method < 0 {
+		err = file.ReadPack()
+	} else {
+		err = file.ReadPackMethod(ggpack.Method(method))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// forEachPack calls fn with the loaded index of every file
+// matching the glob patterns.
+func forEachPack(patterns []string, fn func(string, *ggpack.File) error) error {
+	fnames, err := expandPacks(patterns)
+	if err != nil {
+		return err
+	}
+	for _, fname := range fnames {
+		if err := func() error {
+			index, err := loadIndex(fname)
+			if err != nil {
 				return err
 			}
+			defer index.Close()
+			return fn(fname, index)
+		}(); err != nil {
+			if err == errNoMatch {
+				return err
+			}
+			return fmt.Errorf("%s: %v", fname, err)
 		}
 	}
-
 	return nil
 }
 
-func loadIndex(fname string) (*ggpack.Reader, error) {
+// packs are the opened pack files layered as one namespace.
+type packs struct {
+	*ggpack.Overlay
+	names []string
+	files []*ggpack.File
+}
+
+// expandPacks expands the glob patterns of pack files.
+// Patterns which match nothing are taken literally.
+func expandPacks(patterns []string) ([]string, error) {
+	var fnames []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			matches = []string{pattern}
+		}
+		fnames = append(fnames, matches...)
+	}
+	return fnames, nil
+}
 
-	file, err := os.Open(fname)
+// loadPacks opens the pack files matching the glob patterns.
+// Later packs override the files of earlier ones.
+func loadPacks(patterns []string) (*packs, error) {
+
+	fnames, err := expandPacks(patterns)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	reader := ggpack.Reader{Reader: file}
-	if err := reader.ReadPack(); err != nil {
+	p := &packs{names: fnames}
+
+	readers := make([]*ggpack.Reader, 0, len(fnames))
+	for _, fname := range fnames {
+		index, err := loadIndex(fname)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("%s: %v", fname, err)
+		}
+		p.files = append(p.files, index)
+		readers = append(readers, index.Reader)
+	}
+
+	if p.Overlay, err = ggpack.NewOverlay(readers...); err != nil {
+		p.Close()
 		return nil, err
 	}
-	return &reader, nil
+	return p, nil
 }
 
+// origin returns the name of the pack file the entry is stored in.
+func (p *packs) origin(e *ggpack.OverlayEntry) string {
+	return p.names[e.Pack]
+}
+
+func (p *packs) Close() error {
+	var first error
+	for _, f := range p.files {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// process implements the flag based interface of the older versions.
 func process(fname string) error {
 
-	index, err := loadIndex(fname)
+	index, err := loadPacks([]string{fname})
 	if err != nil {
 		return err
 	}
+	defer index.Close()
 
 	if extractFiles == "" {
-		stdout := bufio.NewWriter(os.Stdout)
-		if err := handleFiles(index, func(name string, _, size int64) error {
-			_, err := fmt.Fprintf(stdout, "%s\t%d\n", name, size)
-			return err
-		}); err != nil {
-			return err
-		}
-		return stdout.Flush()
+		return list(index)
 	}
 
-	re, err := regexp.Compile(extractFiles)
+	match, err := newMatcher([]string{extractFiles}, true)
 	if err != nil {
 		return err
 	}
 
-	return func() error {
-		file, err := os.Open(fname)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		var buf []byte
-		return handleFiles(index, func(name string, ofs, size int64) error {
-			if !re.MatchString(name) {
-				return nil
-			}
-			if _, err := file.Seek(ofs, io.SeekStart); err != nil {
-				return err
-			}
-			if int64(cap(buf)) >= size {
-				buf = buf[:size]
-			} else {
-				buf = make([]byte, size)
-			}
-			_, err := io.ReadFull(file, buf)
-			if err != nil {
-				return err
-			}
-			index.DecodeXOR(buf)
-			if strings.HasSuffix(strings.ToLower(name), ".bnut") {
-				ggpack.DecodeBnut(buf)
-				// remove trailing zeros.
-				buf = trimZeros(buf)
-			}
-			fname := filepath.Join(dir, name)
-			return ioutil.WriteFile(fname, buf, 0666)
-		})
-	}()
+	return extractMatching(index, match)
 }
 
-func trimZeros(buf []byte) []byte {
-	for len(buf) > 0 && buf[len(buf)-1] == 0 {
-		buf = buf[:len(buf)-1]
+func writeJSON(value *ggpack.Value) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s <command> [options] [arguments]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", cmd.name, cmd.descr)
 	}
-	return buf
+	fmt.Fprintf(out, "\nRun '%s <command> -help' for the options of a command.\n", os.Args[0])
+	fmt.Fprintf(out, "\nWithout a command the files are listed or extracted:\n")
+	fmt.Fprintf(out, "  %s [options] <ggpack>...\n\nOptions:\n", os.Args[0])
+	flag.PrintDefaults()
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			os.Exit(cmd.main(os.Args[2:]))
+		}
+	}
+
+	flag.Usage = usage
 	flag.StringVar(&dir, "dir", ".", "directory to extract files to")
 	flag.StringVar(&extractFiles, "extract", "", "pattern of files to files")
+	flag.BoolVar(&dumpJSON, "json", false, "dump index or extracted dictionaries as JSON")
+	methodFlag(flag.CommandLine)
+	flag.IntVar(&workers, "j", 1, "number of files to extract concurrently")
+	flag.BoolVar(&progress, "progress", false, "report progress of extraction")
+	flag.StringVar(&group, "group", "", "group extracted files by 'ext' or 'kind'")
 	flag.Parse()
 
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
 	for _, arg := range flag.Args() {
 		if err := process(arg); err != nil {
-			log.Fatalf("error processing %s: %v\n", arg, err)
+			log.Printf("error processing %s: %v\n", arg, err)
+			os.Exit(exitError)
 		}
 	}
 }
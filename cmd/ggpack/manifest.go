@@ -0,0 +1,323 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+// manifestEntry describes a file of a container. The hash and
+// the size are taken from the decoded content as it is extracted,
+// so .bnut scripts are listed without their padding.
+type manifestEntry struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+var manifestCmd = &command{
+	name:    "manifest",
+	args:    "<ggpack-glob>",
+	descr:   "print the SHA-256 hashes of the files of containers",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpJSON, "json", false, "write the manifest as JSON")
+	},
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args())
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		manifest, err := buildManifest(index)
+		if err != nil {
+			return err
+		}
+		return writeManifest(os.Stdout, manifest)
+	},
+}
+
+var verifyCmd = &command{
+	name:    "verify",
+	args:    "<manifest> <ggpack-glob|directory>",
+	descr:   "check containers or extracted files against a manifest",
+	minArgs: 2,
+	flags: func(flags *flag.FlagSet) {
+		flags.StringVar(&group, "group", "", "the files were extracted grouped by 'ext' or 'kind'")
+	},
+	run: func(flags *flag.FlagSet) error {
+		manifest, err := readManifest(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		target := flags.Arg(1)
+		var report verifyReport
+		if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+			err = report.verifyDir(manifest, target)
+		} else {
+			err = report.verifyPacks(manifest, target)
+		}
+		if err != nil {
+			return err
+		}
+		return report.write(os.Stdout)
+	},
+}
+
+// hashReader returns the SHA-256 hash of the content
+// of r along with its size.
+func hashReader(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func hashEntry(index *packs, e *ggpack.OverlayEntry) (string, int64, error) {
+	in, err := index.OpenEntry(e)
+	if err != nil {
+		return "", 0, err
+	}
+	return hashReader(in)
+}
+
+func buildManifest(index *packs) ([]manifestEntry, error) {
+	manifest := []manifestEntry{}
+	if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+		hash, size, err := hashEntry(index, e)
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.Name, err)
+		}
+		manifest = append(manifest, manifestEntry{
+			Name:   e.Name,
+			Offset: e.Offset,
+			Size:   size,
+			SHA256: hash,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeManifest writes the manifest as JSON if dumpJSON is set.
+// Otherwise one line is written per file containing the hash,
+// the offset, the size and the name separated by spaces.
+func writeManifest(w io.Writer, manifest []manifestEntry) error {
+	if dumpJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(manifest)
+	}
+	out := bufio.NewWriter(w)
+	for i := range manifest {
+		m := &manifest[i]
+		fmt.Fprintf(out, "%s %d %d %s\n", m.SHA256, m.Offset, m.Size, m.Name)
+	}
+	return out.Flush()
+}
+
+// readManifest reads a manifest written by writeManifest
+// in either format.
+func readManifest(fname string) ([]manifestEntry, error) {
+
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var manifest []manifestEntry
+		if err := json.Unmarshal(trimmed, &manifest); err != nil {
+			return nil, fmt.Errorf("%s: %v", fname, err)
+		}
+		return manifest, nil
+	}
+
+	var manifest []manifestEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("%s:%d: malformed line", fname, lineNo)
+		}
+		offset, err1 := strconv.ParseInt(parts[1], 10, 64)
+		size, err2 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil || len(parts[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s:%d: malformed line", fname, lineNo)
+		}
+		manifest = append(manifest, manifestEntry{
+			Name:   parts[3],
+			Offset: offset,
+			Size:   size,
+			SHA256: strings.ToLower(parts[0]),
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// verifyReport collects the results of a verification.
+type verifyReport struct {
+	ok         int
+	mismatched []string
+	missing    []string
+	extra      []string
+}
+
+// verifyPacks checks the files of the containers matching the glob
+// against the manifest. Only the contents are compared as offsets
+// and sizes change when a container is rebuilt.
+func (vr *verifyReport) verifyPacks(manifest []manifestEntry, pattern string) error {
+
+	index, err := loadPacks([]string{pattern})
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	listed := make(map[string]bool, len(manifest))
+	for i := range manifest {
+		m := &manifest[i]
+		listed[strings.ToLower(m.Name)] = true
+		e, err := index.Lookup(m.Name)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			vr.missing = append(vr.missing, m.Name)
+			continue
+		}
+		hash, _, err := hashEntry(index, e)
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.Name, err)
+		}
+		vr.check(m, hash)
+	}
+
+	return handleFiles(index, func(e *ggpack.OverlayEntry) error {
+		if !listed[strings.ToLower(e.Name)] {
+			vr.extra = append(vr.extra, e.Name)
+		}
+		return nil
+	})
+}
+
+// verifyDir checks the files extracted to root against the manifest.
+func (vr *verifyReport) verifyDir(manifest []manifestEntry, root string) error {
+
+	switch group {
+	case "", "ext", "kind":
+	default:
+		return fmt.Errorf("unknown grouping: %q", group)
+	}
+
+	expected := make(map[string]bool, len(manifest))
+	for i := range manifest {
+		m := &manifest[i]
+		fname, err := outputPath(root, m.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Name, err)
+		}
+		expected[fname] = true
+		hash, err := hashFile(fname)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			vr.missing = append(vr.missing, m.Name)
+			continue
+		}
+		vr.check(m, hash)
+	}
+
+	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || expected[path] {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		vr.extra = append(vr.extra, filepath.ToSlash(rel))
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Strings(vr.extra)
+	return nil
+}
+
+func hashFile(fname string) (string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash, _, err := hashReader(f)
+	return hash, err
+}
+
+func (vr *verifyReport) check(m *manifestEntry, hash string) {
+	if hash == m.SHA256 {
+		vr.ok++
+	} else {
+		vr.mismatched = append(vr.mismatched, m.Name)
+	}
+}
+
+// write prints the problems found and a summary. If there are
+// problems errDiffers is returned.
+func (vr *verifyReport) write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, name := range vr.mismatched {
+		fmt.Fprintf(out, "mismatch: %s\n", name)
+	}
+	for _, name := range vr.missing {
+		fmt.Fprintf(out, "missing: %s\n", name)
+	}
+	for _, name := range vr.extra {
+		fmt.Fprintf(out, "extra: %s\n", name)
+	}
+	fmt.Fprintf(out, "%d ok, %d mismatched, %d missing, %d extra\n",
+		vr.ok, len(vr.mismatched), len(vr.missing), len(vr.extra))
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if len(vr.mismatched)+len(vr.missing)+len(vr.extra) > 0 {
+		return errDiffers
+	}
+	return nil
+}
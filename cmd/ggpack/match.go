@@ -0,0 +1,70 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var useRegexp = false
+
+func regexpFlag(flags interface {
+	BoolVar(*bool, string, bool, string)
+}) {
+	flags.BoolVar(&useRegexp, "regexp", false,
+		"patterns are regular expressions instead of globs")
+}
+
+// newMatcher returns a function which reports if a name matches
+// one of the patterns. Globs are matched case-insensitively
+// against the whole name. Without patterns all names match.
+func newMatcher(patterns []string, regexps bool) (func(string) bool, error) {
+
+	if len(patterns) == 0 {
+		return func(string) bool { return true }, nil
+	}
+
+	if regexps {
+		res := make([]*regexp.Regexp, len(patterns))
+		for i, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = re
+		}
+		return func(name string) bool {
+			for _, re := range res {
+				if re.MatchString(name) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	globs := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		globs[i] = strings.ToLower(pattern)
+		// Check the syntax of the pattern early.
+		if _, err := path.Match(globs[i], ""); err != nil {
+			return nil, err
+		}
+	}
+	return func(name string) bool {
+		name = strings.ToLower(name)
+		for _, glob := range globs {
+			if ok, _ := path.Match(glob, name); ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,87 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var queryCmd = &command{
+	name:    "query",
+	args:    "<ggpack-glob> <name> <expr>",
+	descr:   "query values of a dictionary file like a .wimpy room",
+	minArgs: 3,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpJSON, "json", false, "write the matches as JSON array")
+	},
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		e, err := index.Lookup(flags.Arg(1))
+		if err != nil {
+			return err
+		}
+		value, err := decodeEntry(index, e)
+		if err != nil {
+			return err
+		}
+		matches, err := value.Query(flags.Arg(2))
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return errNoMatch
+		}
+		return writeMatches(matches)
+	},
+}
+
+func writeMatches(matches []ggpack.Match) error {
+
+	if dumpJSON {
+		type match struct {
+			Path  string        `json:"path"`
+			Value *ggpack.Value `json:"value"`
+		}
+		out := make([]match, len(matches))
+		for i, m := range matches {
+			out[i] = match{Path: "$" + pathSuffix(m.Path), Value: m.Value}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	stdout := bufio.NewWriter(os.Stdout)
+	for _, m := range matches {
+		data, err := json.Marshal(m.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Path, err)
+		}
+		fmt.Fprintf(stdout, "$%s\t%s\n", pathSuffix(m.Path), data)
+	}
+	return stdout.Flush()
+}
+
+// pathSuffix returns the path to be appended to "$".
+func pathSuffix(path string) string {
+	if path == "" || path[0] == '[' {
+		return path
+	}
+	return "." + path
+}
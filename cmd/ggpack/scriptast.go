@@ -0,0 +1,104 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/s-l-teichmann/ggpack/squirrel"
+	"github.com/s-l-teichmann/ggpack/yack"
+)
+
+var dumpTokens = false
+
+var scriptASTCmd = &command{
+	name:    "script-ast",
+	args:    "<ggpack-glob> <name.bnut>",
+	descr:   "print the syntax tree of a script as JSON",
+	minArgs: 2,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpTokens, "tokens", false, "print the tokens instead")
+	},
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		e, err := index.Lookup(flags.Arg(1))
+		if err != nil {
+			return err
+		}
+		src, err := readEntry(index, e)
+		if err != nil {
+			return err
+		}
+
+		if dumpTokens {
+			return printTokens(e.Name, src)
+		}
+
+		script, perr := squirrel.Parse(src)
+		data, err := squirrel.MarshalJSON(script)
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return err
+		}
+		out.WriteByte('\n')
+		if _, err := out.WriteTo(os.Stdout); err != nil {
+			return err
+		}
+		return syntaxErrors(e.Name, perr)
+	},
+}
+
+// syntaxErrors logs the syntax errors of the named script or dialog.
+func syntaxErrors(name string, err error) error {
+	var list []error
+	switch l := err.(type) {
+	case squirrel.ErrorList:
+		for _, e := range l {
+			list = append(list, e)
+		}
+	case yack.ErrorList:
+		for _, e := range l {
+			list = append(list, e)
+		}
+	default:
+		return err
+	}
+	for _, e := range list {
+		log.Printf("%s:%s\n", name, e)
+	}
+	if len(list) == 1 {
+		return fmt.Errorf("%s: 1 syntax error", name)
+	}
+	return fmt.Errorf("%s: %d syntax errors", name, len(list))
+}
+
+func printTokens(name string, src []byte) error {
+	lexemes, err := squirrel.Tokenize(src)
+	stdout := bufio.NewWriter(os.Stdout)
+	for _, lx := range lexemes {
+		fmt.Fprintf(stdout, "%s\t%s\t%q\n", lx.Pos, lx.Tok, lx.Lit)
+	}
+	if err := stdout.Flush(); err != nil {
+		return err
+	}
+	return syntaxErrors(name, err)
+}
@@ -0,0 +1,74 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+var treeCmd = &command{
+	name:    "tree",
+	args:    "<ggpack-glob>...",
+	descr:   "print the files of containers as a tree",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&humanSizes, "human", false, "print sizes in human readable form")
+	},
+	run: func(flags *flag.FlagSet) error {
+		stdout := bufio.NewWriter(os.Stdout)
+		if err := forEachPack(flags.Args(), func(fname string, index *ggpack.File) error {
+			fsys, err := ggpack.NewFS(index.Reader)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, fname)
+			return printTree(stdout, fsys, ".", "")
+		}); err != nil {
+			return err
+		}
+		return stdout.Flush()
+	},
+}
+
+func printTree(w io.Writer, fsys fs.ReadDirFS, dir, indent string) error {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		branch, next := "├── ", "│   "
+		if i == len(entries)-1 {
+			branch, next = "└── ", "    "
+		}
+		if entry.IsDir() {
+			fmt.Fprintf(w, "%s%s%s/\n", indent, branch, entry.Name())
+			if err := printTree(w, fsys, path.Join(dir, entry.Name()), indent+next); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size := fmt.Sprintf("%d", info.Size())
+		if humanSizes {
+			size = humanSize(info.Size())
+		}
+		fmt.Fprintf(w, "%s%s%s (%s)\n", indent, branch, entry.Name(), size)
+	}
+	return nil
+}
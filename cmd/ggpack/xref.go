@@ -0,0 +1,147 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/s-l-teichmann/ggpack"
+	"github.com/s-l-teichmann/ggpack/squirrel"
+	"github.com/s-l-teichmann/ggpack/xref"
+)
+
+var xrefCmd = &command{
+	name:    "xref",
+	args:    "<ggpack-glob> [symbol]...",
+	descr:   "find definitions and references of symbols in the scripts",
+	minArgs: 1,
+	flags: func(flags *flag.FlagSet) {
+		flags.BoolVar(&dumpJSON, "json", false, "write the results as JSON")
+	},
+	run: func(flags *flag.FlagSet) error {
+		index, err := loadPacks(flags.Args()[:1])
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		x, err := buildXref(index)
+		if err != nil {
+			return err
+		}
+
+		symbols := flags.Args()[1:]
+		if len(symbols) == 0 {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(x.Graph())
+		}
+		return printXref(x, symbols)
+	},
+}
+
+func isScript(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".bnut", ".nut":
+		return true
+	}
+	return false
+}
+
+// buildXref parses all scripts of the containers. Syntax errors
+// are logged and the parsable parts of the scripts are indexed.
+func buildXref(index *packs) (*xref.Index, error) {
+	x := xref.New()
+	var broken int
+	if err := handleFiles(index, func(e *ggpack.OverlayEntry) error {
+		if !isScript(e.Name) {
+			return nil
+		}
+		src, err := readEntry(index, e)
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.Name, err)
+		}
+		script, err := squirrel.Parse(src)
+		if err != nil {
+			broken++
+			log.Printf("warning: %s: %v\n", e.Name, err)
+		}
+		x.Add(e.Name, script)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if broken > 0 {
+		log.Printf("warning: %d scripts with syntax errors\n", broken)
+	}
+	return x, nil
+}
+
+func printXref(x *xref.Index, symbols []string) error {
+
+	type result struct {
+		Symbol      string             `json:"symbol"`
+		Definitions []*xref.Definition `json:"definitions"`
+		References  []*xref.Reference  `json:"references"`
+	}
+
+	var results []result
+	found := false
+	for _, symbol := range symbols {
+		r := result{
+			Symbol:      symbol,
+			Definitions: x.Definitions(symbol),
+			References:  x.References(symbol),
+		}
+		if len(r.Definitions)+len(r.References) > 0 {
+			found = true
+		}
+		results = append(results, r)
+	}
+
+	if dumpJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		stdout := bufio.NewWriter(os.Stdout)
+		for _, r := range results {
+			for _, d := range r.Definitions {
+				fmt.Fprintf(stdout, "%s: %s %s\n", d.Location, d.Kind, d.Symbol)
+			}
+			for _, ref := range r.References {
+				what := "use"
+				if ref.Call {
+					what = "call"
+				}
+				if ref.From != "" {
+					fmt.Fprintf(stdout, "%s: %s of %s in %s\n", ref.Location, what, ref.Symbol, ref.From)
+				} else {
+					fmt.Fprintf(stdout, "%s: %s of %s\n", ref.Location, what, ref.Symbol)
+				}
+			}
+		}
+		if err := stdout.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return errNoMatch
+	}
+	return nil
+}
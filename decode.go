@@ -0,0 +1,256 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type dictDecoder struct {
+	offsets []int32
+	// plo is the position of the string table.
+	plo int
+}
+
+// DecodeDict parses a buffer in the binary dictionary format used
+// for the directory of a ggpack and for files like the .wimpy rooms.
+// The buffer has to be XOR decoded already.
+func DecodeDict(buf []byte) (*Value, error) {
+	v, _, err := decodeDict(buf)
+	return v, err
+}
+
+func decodeDict(buf []byte) (*Value, *dictDecoder, error) {
+	if len(buf) < 4 {
+		return nil, nil, errTooShort
+	}
+	if binary.LittleEndian.Uint32(buf) != 0x04030201 {
+		return nil, nil, errors.New("invalid dictionary signature")
+	}
+
+	var dd dictDecoder
+	if err := dd.readOffsets(buf); err != nil {
+		return nil, nil, err
+	}
+
+	slice := buf[12:]
+	v, err := dd.readHash(&slice, buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, &dd, nil
+}
+
+func readByte(buf *[]byte) (byte, error) {
+	if len(*buf) < 1 {
+		return 0, errTooShort
+	}
+	x := (*buf)[0]
+	*buf = (*buf)[1:]
+	return x, nil
+}
+
+func readInt(buf *[]byte) (int32, error) {
+	if len(*buf) < 4 {
+		return 0, errTooShort
+	}
+	x := int32(binary.LittleEndian.Uint32(*buf))
+	*buf = (*buf)[4:]
+	return x, nil
+}
+
+func (dd *dictDecoder) readHash(buf *[]byte, orig []byte) (*Value, error) {
+
+	t, err := readByte(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if ValueType(t) != HashType {
+		return nil, errors.New("trying to parse non-hash")
+	}
+
+	numEntries, err := readInt(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if numEntries < 0 {
+		return nil, fmt.Errorf("invalid number of hash entries: %d", numEntries)
+	}
+
+	value := Value{typ: HashType}
+
+	value.hash = make(HashEntries, 0, numEntries)
+
+	for i := int32(0); i < numEntries; i++ {
+		offset, err := readInt(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := dd.readString(orig, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := dd.readValue(buf, orig)
+		if err != nil {
+			return nil, err
+		}
+		value.hash = append(value.hash, HashEntry{
+			Key:   key,
+			Value: entry,
+		})
+	}
+	if t, err = readByte(buf); err != nil {
+		return nil, err
+	}
+	if ValueType(t) != HashType {
+		return nil, errors.New("unterminated hash")
+	}
+
+	sort.Slice(value.hash, func(i, j int) bool {
+		return value.hash[i].Key < value.hash[j].Key
+	})
+
+	return &value, nil
+}
+
+func (dd *dictDecoder) readValue(buf *[]byte, orig []byte) (*Value, error) {
+
+	if len(*buf) < 1 {
+		return nil, errTooShort
+	}
+
+	v := Value{typ: ValueType((*buf)[0])}
+
+	switch v.typ {
+	case NullType:
+		*buf = (*buf)[1:]
+		return Null, nil
+	case HashType:
+		return dd.readHash(buf, orig)
+	case ArrayType:
+		*buf = (*buf)[1:]
+		numEntries, err := readInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		if numEntries < 0 {
+			return nil, fmt.Errorf("invalid number of array entries: %d", numEntries)
+		}
+		v.array = make([]*Value, 0, numEntries)
+		for i := int32(0); i < numEntries; i++ {
+			value, err := dd.readValue(buf, orig)
+			if err != nil {
+				return nil, err
+			}
+			v.array = append(v.array, value)
+		}
+		t, err := readByte(buf)
+		if err != nil {
+			return nil, err
+		}
+		if ValueType(t) != ArrayType {
+			return nil, errors.New("unterminated array")
+		}
+
+	case StringType:
+		*buf = (*buf)[1:]
+		ofs, err := readInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		if v.str, err = dd.readString(orig, ofs); err != nil {
+			return nil, err
+		}
+
+	case DoubleType, IntegerType:
+		*buf = (*buf)[1:]
+		ofs, err := readInt(buf)
+		if err != nil {
+			return nil, err
+		}
+		num, err := dd.readString(orig, ofs)
+		if err != nil {
+			return nil, err
+		}
+		if v.typ == IntegerType {
+			var err error
+			if v.integer, err = strconv.ParseInt(num, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid integer: %s", num)
+			}
+		} else {
+			var err error
+			if v.double, err = strconv.ParseFloat(num, 64); err != nil {
+				return nil, fmt.Errorf("invalid double: %s", num)
+			}
+		}
+		v.num = num
+
+	default:
+		return nil, fmt.Errorf("unsupported value: %s", v.typ)
+	}
+
+	return &v, nil
+}
+
+func (dd *dictDecoder) readString(buf []byte, offset int32) (string, error) {
+
+	if offset < 0 || int(offset) >= len(dd.offsets) {
+		return "", fmt.Errorf("invalid offset index: %d", offset)
+	}
+
+	ofs := dd.offsets[offset]
+
+	if ofs < 0 || int(ofs) >= len(buf) {
+		return "", fmt.Errorf("invalid offset: %d", ofs)
+	}
+
+	buf = buf[ofs:]
+
+	end := 0
+
+	for len(buf) > end && buf[end] != 0 {
+		end++
+	}
+
+	return string(buf[:end]), nil
+}
+
+func (dd *dictDecoder) readOffsets(buf []byte) error {
+	if len(buf) < 12 {
+		return errTooShort
+	}
+	plo := binary.LittleEndian.Uint32(buf[8:])
+
+	if plo < 12 || int(plo) >= len(buf)-4 {
+		return errors.New("ggpack plo out of range")
+	}
+	if buf[plo] != 7 {
+		return errors.New("ggpack cannot find plo")
+	}
+
+	dd.plo = int(plo)
+	dd.offsets = dd.offsets[:0]
+
+	for pos := plo + 1; int(pos+4) < len(buf); pos += 4 {
+		offset := binary.LittleEndian.Uint32(buf[pos:])
+		if offset == 0xffffffff {
+			break
+		}
+		dd.offsets = append(dd.offsets, int32(offset))
+
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import "fmt"
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a value only found in the new tree.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved is a value only found in the old tree.
+	ChangeRemoved
+	// ChangeModified is a value found in both trees with a
+	// different type or content.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(k))
+	}
+}
+
+// Change is a difference between two Value trees found by Diff.
+type Change struct {
+	Kind ChangeKind
+	// Path is the path of the value in the notation of the
+	// errors of Unmarshal. It is empty for the roots.
+	Path string
+	// Old is nil for added values.
+	Old *Value
+	// New is nil for removed values.
+	New *Value
+}
+
+// Diff returns the changes needed to turn the tree a into b.
+// Hashes are compared key by key. Arrays are compared element
+// by element, so an element inserted in the middle shows up
+// as modification of all following elements.
+// Hashes and arrays are only reported as modified as a whole
+// if the type of the value changes.
+func Diff(a, b *Value) []Change {
+	var changes []Change
+	diff(orNull(a), orNull(b), "", &changes)
+	return changes
+}
+
+func diff(a, b *Value, path string, changes *[]Change) {
+
+	if a.typ != b.typ {
+		*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+		return
+	}
+
+	switch a.typ {
+	case HashType:
+		i, j := 0, 0
+		for i < len(a.hash) || j < len(b.hash) {
+			switch {
+			case j >= len(b.hash) || (i < len(a.hash) && a.hash[i].Key < b.hash[j].Key):
+				*changes = append(*changes, Change{
+					Kind: ChangeRemoved,
+					Path: keyPath(path, a.hash[i].Key),
+					Old:  a.hash[i].Value,
+				})
+				i++
+			case i >= len(a.hash) || b.hash[j].Key < a.hash[i].Key:
+				*changes = append(*changes, Change{
+					Kind: ChangeAdded,
+					Path: keyPath(path, b.hash[j].Key),
+					New:  b.hash[j].Value,
+				})
+				j++
+			default:
+				diff(orNull(a.hash[i].Value), orNull(b.hash[j].Value),
+					keyPath(path, a.hash[i].Key), changes)
+				i++
+				j++
+			}
+		}
+
+	case ArrayType:
+		for i := 0; i < len(a.array) || i < len(b.array); i++ {
+			switch {
+			case i >= len(b.array):
+				*changes = append(*changes, Change{
+					Kind: ChangeRemoved,
+					Path: indexPath(path, i),
+					Old:  a.array[i],
+				})
+			case i >= len(a.array):
+				*changes = append(*changes, Change{
+					Kind: ChangeAdded,
+					Path: indexPath(path, i),
+					New:  b.array[i],
+				})
+			default:
+				diff(orNull(a.array[i]), orNull(b.array[i]), indexPath(path, i), changes)
+			}
+		}
+
+	default:
+		if !a.scalarEqual(b) {
+			*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+		}
+	}
+}
+
+// scalarEqual compares two scalar values of the same type.
+func (v *Value) scalarEqual(o *Value) bool {
+	switch v.typ {
+	case StringType:
+		return v.str == o.str
+	case IntegerType:
+		return v.integer == o.integer
+	case DoubleType:
+		return v.double == o.double
+	default:
+		return true
+	}
+}
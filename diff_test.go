@@ -0,0 +1,91 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"strings"
+	"testing"
+)
+
+// changeString formats a change as "kind path: old -> new".
+func changeString(t *testing.T, c Change) string {
+	t.Helper()
+	s := c.Kind.String() + " " + c.Path + ":"
+	if c.Old != nil {
+		s += " " + jsonString(t, c.Old)
+	}
+	s += " ->"
+	if c.New != nil {
+		s += " " + jsonString(t, c.New)
+	}
+	return s
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{"equal", `{"a": [1, {"b": "x"}], "n": null}`, `{"a": [1, {"b": "x"}], "n": null}`, nil},
+		{"added key", `{"a": 1}`, `{"a": 1, "b": 2}`,
+			[]string{"added b: -> 2"}},
+		{"removed key", `{"a": 1, "b": 2}`, `{"b": 2}`,
+			[]string{"removed a: 1 ->"}},
+		{"changed keys", `{"a": 1, "b": "x", "c": 1.5}`, `{"a": 2, "b": "y", "c": 2.5}`,
+			[]string{
+				"modified a: 1 -> 2",
+				"modified b: \"x\" -> \"y\"",
+				"modified c: 1.5 -> 2.5",
+			}},
+		{"interleaved keys", `{"a": 1, "c": 3, "e": 5}`, `{"b": 2, "c": 3, "d": 4}`,
+			[]string{
+				"removed a: 1 ->",
+				"added b: -> 2",
+				"added d: -> 4",
+				"removed e: 5 ->",
+			}},
+		{"nested", `{"o": {"p": [1, {"q": 1}]}}`, `{"o": {"p": [1, {"q": 2}]}}`,
+			[]string{"modified o.p[1].q: 1 -> 2"}},
+		{"array grows", `{"a": [1, 2]}`, `{"a": [1, 2, 3, 4]}`,
+			[]string{"added a[2]: -> 3", "added a[3]: -> 4"}},
+		{"array shrinks", `{"a": [1, 2, 3]}`, `{"a": [1]}`,
+			[]string{"removed a[1]: 2 ->", "removed a[2]: 3 ->"}},
+		{"array insert in the middle", `{"a": [1, 3]}`, `{"a": [1, 2, 3]}`,
+			[]string{"modified a[1]: 3 -> 2", "added a[2]: -> 3"}},
+		{"type change scalar", `{"a": 1}`, `{"a": "1"}`,
+			[]string{`modified a: 1 -> "1"`}},
+		{"type change integer to double", `{"a": 1}`, `{"a": 1.0}`,
+			[]string{"modified a: 1 -> 1.0"}},
+		{"type change to hash", `{"a": [1]}`, `{"a": {"b": 1}}`,
+			[]string{`modified a: [1] -> {"b":1}`}},
+		{"type change to null", `{"a": {"b": 1}}`, `{"a": null}`,
+			[]string{`modified a: {"b":1} -> null`}},
+		{"root type change", `{"a": 1}`, `[1]`,
+			[]string{`modified : {"a":1} -> [1]`}},
+	} {
+		var got []string
+		for _, c := range Diff(jsonValue(t, tc.old), jsonValue(t, tc.new)) {
+			got = append(got, changeString(t, c))
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.name,
+				strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestDiffNil(t *testing.T) {
+	if changes := Diff(nil, Null); len(changes) != 0 {
+		t.Errorf("nil and Null differ: %v", changes)
+	}
+	changes := Diff(nil, NewHash())
+	if len(changes) != 1 || changes[0].Kind != ChangeModified || changes[0].Old != Null {
+		t.Errorf("nil against hash: %v", changes)
+	}
+}
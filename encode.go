@@ -0,0 +1,144 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type dictEncoder struct {
+	buf     []byte
+	strings []string
+	indices map[string]int32
+}
+
+func (de *dictEncoder) writeByte(x byte) {
+	de.buf = append(de.buf, x)
+}
+
+func (de *dictEncoder) writeInt(x int32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(x))
+	de.buf = append(de.buf, b[:]...)
+}
+
+func (de *dictEncoder) writeString(s string) error {
+	idx, ok := de.indices[s]
+	if !ok {
+		if strings.IndexByte(s, 0) >= 0 {
+			return fmt.Errorf("string contains NUL byte: %q", s)
+		}
+		idx = int32(len(de.strings))
+		de.strings = append(de.strings, s)
+		de.indices[s] = idx
+	}
+	de.writeInt(idx)
+	return nil
+}
+
+// number returns the textual representation of an integer or double.
+// The text found while decoding is preferred to be able to reproduce
+// the original dictionary.
+func (v *Value) number() string {
+	if v.num != "" {
+		return v.num
+	}
+	if v.typ == IntegerType {
+		return strconv.FormatInt(v.integer, 10)
+	}
+	return strconv.FormatFloat(v.double, 'g', -1, 64)
+}
+
+func (de *dictEncoder) writeValue(v *Value) error {
+
+	if v == nil {
+		v = Null
+	}
+
+	de.writeByte(byte(v.typ))
+
+	switch v.typ {
+	case NullType:
+
+	case HashType:
+		de.writeInt(int32(len(v.hash)))
+		for i := range v.hash {
+			if err := de.writeString(v.hash[i].Key); err != nil {
+				return err
+			}
+			if err := de.writeValue(v.hash[i].Value); err != nil {
+				return err
+			}
+		}
+		de.writeByte(byte(HashType))
+
+	case ArrayType:
+		de.writeInt(int32(len(v.array)))
+		for _, e := range v.array {
+			if err := de.writeValue(e); err != nil {
+				return err
+			}
+		}
+		de.writeByte(byte(ArrayType))
+
+	case StringType:
+		return de.writeString(v.str)
+
+	case IntegerType, DoubleType:
+		return de.writeString(v.number())
+
+	default:
+		return fmt.Errorf("unsupported value: %s", v.typ)
+	}
+
+	return nil
+}
+
+// EncodeDict serializes a hash value into the binary dictionary
+// format used for the directory of a ggpack and for files like
+// the .wimpy rooms. Equal strings are stored only once.
+// The result is not XOR encoded.
+func EncodeDict(v *Value) ([]byte, error) {
+
+	if v == nil || v.typ != HashType {
+		return nil, errors.New("trying to encode non-hash")
+	}
+
+	de := dictEncoder{indices: map[string]int32{}}
+
+	de.writeInt(0x04030201)
+	de.writeInt(1)
+	de.writeInt(0) // plo, patched below
+
+	if err := de.writeValue(v); err != nil {
+		return nil, err
+	}
+
+	plo := len(de.buf)
+	binary.LittleEndian.PutUint32(de.buf[8:], uint32(plo))
+
+	de.writeByte(7)
+	table := len(de.buf)
+	for range de.strings {
+		de.writeInt(0) // offset, patched below
+	}
+	de.writeInt(-1)
+
+	de.writeByte(8)
+	for i, s := range de.strings {
+		binary.LittleEndian.PutUint32(de.buf[table+4*i:], uint32(len(de.buf)))
+		de.buf = append(de.buf, s...)
+		de.writeByte(0)
+	}
+
+	return de.buf, nil
+}
@@ -0,0 +1,209 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func le32(x int32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(x))
+	return b[:]
+}
+
+// rawDict assembles a dictionary the way the game stores them
+// from the encoded root hash and the string table.
+func rawDict(value []byte, strs ...string) []byte {
+	var buf []byte
+	buf = append(buf, le32(0x04030201)...)
+	buf = append(buf, le32(1)...)
+	buf = append(buf, le32(int32(12+len(value)))...)
+	buf = append(buf, value...)
+	buf = append(buf, 7)
+	ofs := len(buf) + 4*len(strs) + 4 + 1
+	for _, s := range strs {
+		buf = append(buf, le32(int32(ofs))...)
+		ofs += len(s) + 1
+	}
+	buf = append(buf, le32(-1)...)
+	buf = append(buf, 8)
+	for _, s := range strs {
+		buf = append(buf, s...)
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// ref encodes a string reference of the given type.
+func ref(typ ValueType, idx int32) []byte {
+	return append([]byte{byte(typ)}, le32(idx)...)
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestEncodeDictRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    *Value
+		// strings is the expected number of strings in the table.
+		strings int
+	}{
+		{"empty hash", NewHash(), 0},
+		{"empty children",
+			NewHash(
+				HashEntry{"a", NewArray()},
+				HashEntry{"h", NewHash()}),
+			2},
+		{"null",
+			NewHash(
+				HashEntry{"n", Null},
+				HashEntry{"l", NewArray(nil, Null)}),
+			2},
+		{"numbers",
+			NewHash(
+				HashEntry{"i", NewInteger(-42)},
+				HashEntry{"z", NewInteger(0)},
+				HashEntry{"d", NewDouble(-0.25)},
+				HashEntry{"e", NewDouble(1e21)}),
+			8},
+		{"string de-duplication",
+			NewHash(
+				HashEntry{"a", NewString("x")},
+				HashEntry{"b", NewString("x")},
+				HashEntry{"x", NewArray(NewString("a"), NewString("x"))}),
+			3},
+		{"numbers sharing text",
+			NewHash(
+				HashEntry{"1", NewInteger(1)},
+				HashEntry{"s", NewString("1")}),
+			2},
+		{"nested",
+			NewHash(HashEntry{"rooms", NewArray(
+				NewHash(
+					HashEntry{"name", NewString("Bank")},
+					HashEntry{"size", NewString("{640,180}")}),
+				NewHash(
+					HashEntry{"name", NewString("Diner")},
+					HashEntry{"size", NewString("{640,180}")}),
+			)}),
+			6},
+	} {
+		data, err := EncodeDict(tc.v)
+		if err != nil {
+			t.Errorf("%s: encoding failed: %v", tc.name, err)
+			continue
+		}
+		got, dd, err := decodeDict(data)
+		if err != nil {
+			t.Errorf("%s: decoding failed: %v", tc.name, err)
+			continue
+		}
+		if changes := Diff(tc.v, got); len(changes) > 0 {
+			t.Errorf("%s: decoded value differs: %+v", tc.name, changes)
+		}
+		if n := len(dd.offsets); n != tc.strings {
+			t.Errorf("%s: %d strings, want %d", tc.name, n, tc.strings)
+		}
+		again, err := EncodeDict(got)
+		if err != nil {
+			t.Errorf("%s: encoding decoded value failed: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("%s: re-encoded bytes differ:\n%x\nwant\n%x", tc.name, again, data)
+		}
+	}
+}
+
+func TestEncodeDictKeepsBytes(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"empty", rawDict(cat([]byte{byte(HashType)}, le32(0), []byte{byte(HashType)}))},
+		{"number text",
+			rawDict(cat(
+				[]byte{byte(HashType)}, le32(3),
+				le32(0), ref(DoubleType, 1),
+				le32(2), ref(IntegerType, 3),
+				le32(4), ref(DoubleType, 5),
+				[]byte{byte(HashType)}),
+				"d", "1.50", "i", "-07", "x", "1e3")},
+		{"shared strings",
+			rawDict(cat(
+				[]byte{byte(HashType)}, le32(2),
+				le32(0), ref(StringType, 1),
+				le32(1), []byte{byte(ArrayType)}, le32(3),
+				ref(StringType, 0), []byte{byte(NullType)}, ref(IntegerType, 2),
+				[]byte{byte(ArrayType)},
+				[]byte{byte(HashType)}),
+				"a", "b", "-1")},
+	} {
+		v, err := DecodeDict(tc.data)
+		if err != nil {
+			t.Errorf("%s: decoding failed: %v", tc.name, err)
+			continue
+		}
+		got, err := EncodeDict(v)
+		if err != nil {
+			t.Errorf("%s: encoding failed: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.data) {
+			t.Errorf("%s: got\n%x\nwant\n%x", tc.name, got, tc.data)
+		}
+	}
+}
+
+func TestEncodeDictNumberValues(t *testing.T) {
+	data := rawDict(cat(
+		[]byte{byte(HashType)}, le32(2),
+		le32(0), ref(DoubleType, 1),
+		le32(2), ref(IntegerType, 3),
+		[]byte{byte(HashType)}),
+		"d", "1.50", "i", "-07")
+	v, err := DecodeDict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := v.Find("d"); d == nil || d.Double() != 1.5 {
+		t.Errorf("d = %v, want 1.5", d)
+	}
+	if i := v.Find("i"); i == nil || i.Integer() != -7 {
+		t.Errorf("i = %v, want -7", i)
+	}
+
+	// The text of numbers is only kept for EncodeDict.
+	for _, n := range []*Value{v.Find("d"), v.Find("i"), NewInteger(1), NewDouble(0.5)} {
+		if s := n.String(); s != "" {
+			t.Errorf("String() of %s %v is %q, want \"\"", n.Type(), n, s)
+		}
+	}
+}
+
+func TestEncodeDictErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    *Value
+	}{
+		{"nil", nil},
+		{"array", NewArray()},
+		{"string", NewString("x")},
+		{"NUL in string", NewHash(HashEntry{"a", NewString("x\x00y")})},
+		{"NUL in key", NewHash(HashEntry{"a\x00", Null})},
+	} {
+		if _, err := EncodeDict(tc.v); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"os"
+)
+
+// File is a Reader over a ggpack file opened with OpenFile.
+type File struct {
+	*Reader
+	file *os.File
+	data []byte
+}
+
+// OpenFile opens the named ggpack file for concurrent reading.
+// On Linux the file is memory mapped. ReadPack or ReadPackMethod
+// has to be called before using the returned File.
+// The content of the files must not be accessed after Close.
+func OpenFile(fname string) (*File, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	data, err := mapFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if data == nil {
+		return &File{Reader: NewReaderAt(file), file: file}, nil
+	}
+	// The mapping stays valid after closing the file.
+	if err := file.Close(); err != nil {
+		unmapFile(data)
+		return nil, err
+	}
+	return &File{Reader: NewReaderAt(bytes.NewReader(data)), data: data}, nil
+}
+
+// Close releases the resources of the file.
+func (f *File) Close() error {
+	if f.data != nil {
+		data := f.data
+		f.data = nil
+		return unmapFile(data)
+	}
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+)
+
+// Entry is a file stored in a ggpack container.
+type Entry struct {
+	Name   string
+	Offset int64
+	Size   int64
+}
+
+func entryField(f *Value, i int, key string, typ ValueType) (*Value, error) {
+	v := f.Find(key)
+	if v == nil {
+		return nil, fmt.Errorf("files[%d].%s: missing", i, key)
+	}
+	if v.Type() != typ {
+		return nil, fmt.Errorf("files[%d].%s: expected %s, got %s",
+			i, key, typ, v.Type())
+	}
+	return v, nil
+}
+
+func (r *Reader) readFiles() error {
+
+	files := r.Entries().Find("files")
+	if files == nil || files.Type() != ArrayType {
+		return errors.New("no files found")
+	}
+
+	entries := make([]Entry, 0, len(files.Array()))
+	lookup := make(map[string]int, len(files.Array()))
+
+	for i, f := range files.Array() {
+		if f.Type() != HashType {
+			return fmt.Errorf("files[%d]: expected %s, got %s", i, HashType, f.Type())
+		}
+		name, err := entryField(f, i, "filename", StringType)
+		if err != nil {
+			return err
+		}
+		ofs, err := entryField(f, i, "offset", IntegerType)
+		if err != nil {
+			return err
+		}
+		size, err := entryField(f, i, "size", IntegerType)
+		if err != nil {
+			return err
+		}
+		if ofs.Integer() < 0 || size.Integer() < 0 {
+			return fmt.Errorf("files[%d]: invalid offset/size: %d/%d",
+				i, ofs.Integer(), size.Integer())
+		}
+		lookup[strings.ToLower(name.String())] = len(entries)
+		entries = append(entries, Entry{
+			Name:   name.String(),
+			Offset: ofs.Integer(),
+			Size:   size.Integer(),
+		})
+	}
+
+	r.files, r.lookup = entries, lookup
+	return nil
+}
+
+// Files returns the files stored in the container in the order
+// of the directory. Malformed entries are reported as errors.
+// The returned slice must not be modified.
+func (r *Reader) Files() ([]Entry, error) {
+	r.filesMu.Lock()
+	defer r.filesMu.Unlock()
+	if r.files == nil {
+		if err := r.readFiles(); err != nil {
+			return nil, err
+		}
+	}
+	return r.files, nil
+}
+
+// Lookup finds the file with the given name. Like in the game
+// the name is compared case-insensitively. If the file does not
+// exist the returned error wraps fs.ErrNotExist.
+func (r *Reader) Lookup(name string) (*Entry, error) {
+	files, err := r.Files()
+	if err != nil {
+		return nil, err
+	}
+	idx, ok := r.lookup[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return &files[idx], nil
+}
+
+func isBnut(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".bnut")
+}
+
+// seekerAt adapts an io.ReadSeeker to an io.ReaderAt.
+// It is not safe for concurrent use.
+type seekerAt struct{ rs io.ReadSeeker }
+
+func (sa seekerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := sa.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(sa.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *Reader) readerAt() io.ReaderAt {
+	if r.ra != nil {
+		return r.ra
+	}
+	if ra, ok := r.Reader.(io.ReaderAt); ok {
+		return ra
+	}
+	return seekerAt{r.Reader}
+}
+
+// concurrent reports if entries can be read concurrently.
+func (r *Reader) concurrent() bool {
+	_, ok := r.readerAt().(seekerAt)
+	return !ok
+}
+
+// OpenEntry returns a reader which decodes the content of the given file
+// while reading. The NUL padding of .bnut scripts is removed.
+// ReadAt of the returned reader may be called in parallel
+// if r reads from an io.ReaderAt.
+func (r *Reader) OpenEntry(e *Entry) (ReadSeekerAt, error) {
+	er := NewEntryReader(r.readerAt(), e.Offset, e.Size, r.method)
+	if isBnut(e.Name) {
+		return NewBnutReader(er, e.Size)
+	}
+	return er, nil
+}
+
+func (r *Reader) readEntry(e *Entry) ([]byte, error) {
+	rd, err := r.OpenEntry(e)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(rd)
+}
+
+// Open returns the decoded content of the file with the given name.
+// The file is looked up with Lookup. The content is decoded while
+// reading. The NUL padding of .bnut scripts is removed.
+// Reading from the returned reader is only safe concurrently to
+// other operations on r if r reads from an io.ReaderAt.
+func (r *Reader) Open(name string) (io.ReadCloser, error) {
+	e, err := r.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := r.OpenEntry(e)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(rd), nil
+}
+
+// Info holds some statistics about a ggpack container.
+type Info struct {
+	Method          Method
+	DirectoryOffset int64
+	DirectorySize   int64
+	Entries         int
+	Strings         int
+	StringTableSize int64
+	PayloadSize     int64
+}
+
+// Info returns statistics about the container.
+// Strings is the number of entries in the string table of the
+// directory and StringTableSize its size in bytes including the
+// offsets. PayloadSize is the sum of the sizes of the files.
+func (r *Reader) Info() (*Info, error) {
+	files, err := r.Files()
+	if err != nil {
+		return nil, err
+	}
+	var payload int64
+	for i := range files {
+		payload += files[i].Size
+	}
+	return &Info{
+		Method:          r.method,
+		DirectoryOffset: r.dirOffset,
+		DirectorySize:   r.dirSize,
+		Entries:         len(files),
+		Strings:         r.numStrings,
+		StringTableSize: r.stringsSize,
+		PayloadSize:     payload,
+	}, nil
+}
@@ -0,0 +1,262 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FS provides the files of a ggpack container as a read-only file system.
+// The directories are synthesized from the slashes in the file names.
+// The content of the files is XOR decoded and .bnut scripts
+// are decoded with DecodeBnut, too. Their NUL padding is removed.
+type FS struct {
+	reader *Reader
+	mu     sync.Mutex
+	nodes  map[string]*fsNode
+}
+
+type fsNode struct {
+	name     string
+	dir      bool
+	entry    *Entry
+	size     int64
+	children []*fsNode
+}
+
+type fsFile struct {
+	node   *fsNode
+	reader *bytes.Reader
+}
+
+type fsDir struct {
+	node *fsNode
+	pos  int
+}
+
+var (
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// NewFS creates a file system over the files of the given Reader.
+// ReadPack has to be called on the Reader before.
+// Unless the Reader reads from an io.ReaderAt it must not be
+// used concurrently to the file system.
+func NewFS(r *Reader) (*FS, error) {
+
+	files, err := r.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	root := &fsNode{name: ".", dir: true}
+	fsys := &FS{
+		reader: r,
+		nodes:  map[string]*fsNode{".": root},
+	}
+
+	for i := range files {
+		if err := fsys.add(&files[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, n := range fsys.nodes {
+		sort.Slice(n.children, func(i, j int) bool {
+			return n.children[i].name < n.children[j].name
+		})
+	}
+
+	return fsys, nil
+}
+
+func (fsys *FS) add(e *Entry) error {
+
+	name := e.Name
+
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid file name: %q", name)
+	}
+	if _, found := fsys.nodes[name]; found {
+		return fmt.Errorf("duplicate file name: %q", name)
+	}
+
+	size := e.Size
+	if isBnut(name) {
+		// The padding is not part of the content.
+		rd, err := fsys.reader.OpenEntry(e)
+		if err != nil {
+			return err
+		}
+		if size, err = rd.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	node := &fsNode{name: path.Base(name), entry: e, size: size}
+	fsys.nodes[name] = node
+
+	for {
+		dir := path.Dir(name)
+		parent := fsys.nodes[dir]
+		if parent == nil {
+			parent = &fsNode{name: path.Base(dir), dir: true}
+			fsys.nodes[dir] = parent
+			parent.children = append(parent.children, node)
+			name, node = dir, parent
+			continue
+		}
+		if !parent.dir {
+			return fmt.Errorf("file used as directory: %q", dir)
+		}
+		parent.children = append(parent.children, node)
+		return nil
+	}
+}
+
+func (fsys *FS) lookup(op, name string) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node := fsys.nodes[name]
+	if node == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+func (fsys *FS) read(node *fsNode) ([]byte, error) {
+	if !fsys.reader.concurrent() {
+		fsys.mu.Lock()
+		defer fsys.mu.Unlock()
+	}
+	return fsys.reader.readEntry(node.entry)
+}
+
+// Open implements fs.FS.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	node, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.dir {
+		return &fsDir{node: node}, nil
+	}
+	data, err := fsys.read(node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{node: node, reader: bytes.NewReader(data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	node, err := fsys.lookup("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.dir {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errIsDir}
+	}
+	data, err := fsys.read(node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	entries := make([]fs.DirEntry, len(node.children))
+	for i, child := range node.children {
+		entries[i] = child
+	}
+	return entries, nil
+}
+
+// Stat implements fs.StatFS.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	node, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+func (n *fsNode) Name() string               { return n.name }
+func (n *fsNode) IsDir() bool                { return n.dir }
+func (n *fsNode) ModTime() time.Time         { return time.Time{} }
+func (n *fsNode) Sys() interface{}           { return nil }
+func (n *fsNode) Type() fs.FileMode          { return n.Mode().Type() }
+func (n *fsNode) Info() (fs.FileInfo, error) { return n, nil }
+
+func (n *fsNode) Size() int64 {
+	if n.dir {
+		return 0
+	}
+	return n.size
+}
+
+func (n *fsNode) Mode() fs.FileMode {
+	if n.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error)                { return f.node, nil }
+func (f *fsFile) Read(p []byte) (int, error)                { return f.reader.Read(p) }
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error)   { return f.reader.ReadAt(p, off) }
+func (f *fsFile) Seek(off int64, whence int) (int64, error) { return f.reader.Seek(off, whence) }
+func (f *fsFile) Close() error                              { return nil }
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.node, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.node.name, Err: errIsDir}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *fsDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := d.node.children[d.pos:]
+	if count > 0 && len(rest) > count {
+		rest = rest[:count]
+	}
+	if count > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	d.pos += len(rest)
+	entries := make([]fs.DirEntry, len(rest))
+	for i, child := range rest {
+		entries[i] = child
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	files := map[string][]byte{
+		"Boot.bnut":               randomScript(22, 5),
+		"empty.txt":               {},
+		"rooms/Bank.wimpy":        randomBytes(100, 6),
+		"rooms/deep/Diner.wimpy":  randomBytes(7, 7),
+		"scripts/actors/Ray.bnut": randomScript(5, 8),
+	}
+	fsys, err := NewFS(writePack(t, MethodDefault, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	if err := fstest.TestFS(fsys, names...); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range files {
+		got, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatalf("reading %s failed: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("content of %s differs", name)
+		}
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			t.Fatalf("stat of %s failed: %v", name, err)
+		}
+		if info.Size() != int64(len(want)) {
+			t.Errorf("size of %s is %d, want %d", name, info.Size(), len(want))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON implements json.Marshaler.
+// Hashes are written as objects with their keys in order,
+// doubles always carry a decimal point or an exponent to be
+// distinguishable from integers.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := v.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func (v *Value) writeJSON(buf *bytes.Buffer) error {
+
+	if v == nil {
+		buf.WriteString("null")
+		return nil
+	}
+
+	switch v.typ {
+	case NullType:
+		buf.WriteString("null")
+
+	case HashType:
+		buf.WriteByte('{')
+		for i := range v.hash {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONString(buf, v.hash[i].Key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := v.hash[i].Value.writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case ArrayType:
+		buf.WriteByte('[')
+		for i, e := range v.array {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := e.writeJSON(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case StringType:
+		return writeJSONString(buf, v.str)
+
+	case IntegerType:
+		buf.WriteString(strconv.FormatInt(v.integer, 10))
+
+	case DoubleType:
+		if math.IsNaN(v.double) || math.IsInf(v.double, 0) {
+			return fmt.Errorf("unsupported double: %g", v.double)
+		}
+		s := strconv.FormatFloat(v.double, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		buf.WriteString(s)
+
+	default:
+		return fmt.Errorf("unsupported value: %s", v.typ)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Numbers without a decimal point or an exponent become integers,
+// all other numbers become doubles. Booleans are not supported.
+// If a key appears more than once in an object the last one wins.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := readJSONValue(dec)
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("trailing data after JSON value")
+	}
+	*v = *value
+	return nil
+}
+
+func readJSONValue(dec *json.Decoder) (*Value, error) {
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case nil:
+		return Null, nil
+
+	case string:
+		return &Value{typ: StringType, str: t}, nil
+
+	case json.Number:
+		s := string(t)
+		if strings.ContainsAny(s, ".eE") {
+			d, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid double: %s", s)
+			}
+			return &Value{typ: DoubleType, double: d}, nil
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer: %s", s)
+		}
+		return &Value{typ: IntegerType, integer: i}, nil
+
+	case json.Delim:
+		switch t {
+		case '{':
+			v := &Value{typ: HashType, hash: HashEntries{}}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := tok.(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected JSON token: %v", tok)
+				}
+				value, err := readJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				// Like encoding/json the last of duplicate keys wins.
+				v.Set(key, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return v, nil
+
+		case '[':
+			v := &Value{typ: ArrayType, array: []*Value{}}
+			for dec.More() {
+				value, err := readJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				v.array = append(v.array, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported JSON value: %v", tok)
+}
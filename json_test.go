@@ -0,0 +1,29 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import "testing"
+
+func TestUnmarshalJSONDuplicateKeys(t *testing.T) {
+	var v Value
+	if err := v.UnmarshalJSON([]byte(`{"b": 1, "a": 2, "b": 3}`)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if n := len(v.Hash()); n != 2 {
+		t.Fatalf("got %d entries, want 2", n)
+	}
+	if b := v.Find("b"); b == nil || b.Integer() != 3 {
+		t.Errorf("b = %v, want 3", b)
+	}
+	if a := v.Find("a"); a == nil || a.Integer() != 2 {
+		t.Errorf("a = %v, want 2", a)
+	}
+	if _, err := EncodeDict(&v); err != nil {
+		t.Errorf("encoding failed: %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var valueType = reflect.TypeOf((*Value)(nil))
+
+// field is an exported field of a struct mapped to a hash key.
+type field struct {
+	key       string
+	index     int
+	omitEmpty bool
+}
+
+// structFields returns the fields of a struct type. The keys are
+// taken from the ggpack tags. Without a tag the field name is used.
+// Fields tagged with "-" are ignored.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i, n := 0, t.NumField(); i < n; i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("ggpack")
+		if tag == "-" {
+			continue
+		}
+		f := field{key: sf.Name, index: i}
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				f.key = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" {
+					f.omitEmpty = true
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func findField(fields []field, key string) *field {
+	for i := range fields {
+		if fields[i].key == key {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].key, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func keyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func pathError(path string, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if path == "" {
+		return errors.New(msg)
+	}
+	return errors.New(path + ": " + msg)
+}
+
+// Unmarshal stores the content of v in the value pointed to by out.
+// Hashes are stored in structs and maps with string keys, arrays in
+// slices and arrays. The keys of the hashes are matched against the
+// ggpack tags of the struct fields or their names if untagged,
+// preferring exact matches. Keys without a field are ignored.
+// Integers are stored in integer, floating point and bool fields,
+// doubles in floating point fields. Values which do not fit into
+// the fields are errors. Null sets fields to their zero values.
+// Values are stored in interface{} fields as map[string]interface{},
+// []interface{}, string, int64, float64 or nil and *Value fields
+// get the value itself. Errors carry the path of the offending value
+// like "files[12].offset: expected integer, got string".
+func Unmarshal(v *Value, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unmarshal needs a non-nil pointer, got %T", out)
+	}
+	return unmarshal(v, rv.Elem(), "")
+}
+
+func unmarshal(v *Value, rv reflect.Value, path string) error {
+
+	if v == nil {
+		v = Null
+	}
+
+	if rv.Type() == valueType {
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	if v.typ == NullType {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	expect := func(typ ValueType) error {
+		if v.typ != typ {
+			return pathError(path, "expected %s, got %s", typ, v.typ)
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshal(v, rv.Elem(), path)
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return pathError(path, "unsupported type %s", rv.Type())
+		}
+		x, err := v.generic(path)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+		} else {
+			rv.Set(reflect.ValueOf(x))
+		}
+		return nil
+
+	case reflect.Struct:
+		if err := expect(HashType); err != nil {
+			return err
+		}
+		fields := structFields(rv.Type())
+		for _, e := range v.hash {
+			f := findField(fields, e.Key)
+			if f == nil {
+				continue
+			}
+			if err := unmarshal(e.Value, rv.Field(f.index), keyPath(path, e.Key)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return pathError(path, "unsupported type %s", rv.Type())
+		}
+		if err := expect(HashType); err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(v.hash)))
+		}
+		et := rv.Type().Elem()
+		for _, e := range v.hash {
+			ev := reflect.New(et).Elem()
+			if err := unmarshal(e.Value, ev, keyPath(path, e.Key)); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(e.Key).Convert(rv.Type().Key()), ev)
+		}
+		return nil
+
+	case reflect.Slice:
+		if err := expect(ArrayType); err != nil {
+			return err
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), len(v.array), len(v.array)))
+		for i, e := range v.array {
+			if err := unmarshal(e, rv.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Array:
+		if err := expect(ArrayType); err != nil {
+			return err
+		}
+		if len(v.array) > rv.Len() {
+			return pathError(path, "%d elements do not fit into %s",
+				len(v.array), rv.Type())
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if i < len(v.array) {
+				if err := unmarshal(v.array[i], rv.Index(i), indexPath(path, i)); err != nil {
+					return err
+				}
+			} else {
+				rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+			}
+		}
+		return nil
+
+	case reflect.String:
+		if err := expect(StringType); err != nil {
+			return err
+		}
+		rv.SetString(v.str)
+		return nil
+
+	case reflect.Bool:
+		if err := expect(IntegerType); err != nil {
+			return err
+		}
+		rv.SetBool(v.integer != 0)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := expect(IntegerType); err != nil {
+			return err
+		}
+		if rv.OverflowInt(v.integer) {
+			return pathError(path, "%d out of range for %s", v.integer, rv.Type())
+		}
+		rv.SetInt(v.integer)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if err := expect(IntegerType); err != nil {
+			return err
+		}
+		if v.integer < 0 || rv.OverflowUint(uint64(v.integer)) {
+			return pathError(path, "%d out of range for %s", v.integer, rv.Type())
+		}
+		rv.SetUint(uint64(v.integer))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var d float64
+		switch v.typ {
+		case DoubleType:
+			d = v.double
+		case IntegerType:
+			d = float64(v.integer)
+		default:
+			return pathError(path, "expected %s, got %s", DoubleType, v.typ)
+		}
+		if rv.OverflowFloat(d) {
+			return pathError(path, "%g out of range for %s", d, rv.Type())
+		}
+		rv.SetFloat(d)
+		return nil
+	}
+
+	return pathError(path, "unsupported type %s", rv.Type())
+}
+
+// generic converts v into a map[string]interface{}, []interface{},
+// string, int64, float64 or nil.
+func (v *Value) generic(path string) (interface{}, error) {
+	switch v.typ {
+	case NullType:
+		return nil, nil
+	case HashType:
+		m := make(map[string]interface{}, len(v.hash))
+		for _, e := range v.hash {
+			x, err := e.Value.generic(keyPath(path, e.Key))
+			if err != nil {
+				return nil, err
+			}
+			m[e.Key] = x
+		}
+		return m, nil
+	case ArrayType:
+		a := make([]interface{}, len(v.array))
+		for i, e := range v.array {
+			x, err := e.generic(indexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			a[i] = x
+		}
+		return a, nil
+	case StringType:
+		return v.str, nil
+	case IntegerType:
+		return v.integer, nil
+	case DoubleType:
+		return v.double, nil
+	}
+	return nil, pathError(path, "unsupported value: %s", v.typ)
+}
+
+// Marshal converts in into a Value. It is the reverse of Unmarshal:
+// Structs and maps with string keys become hashes sorted by key,
+// slices and arrays become arrays. Integers and bools become integers,
+// floating point numbers become doubles. Nil pointers, interfaces,
+// maps and slices become Null. Struct fields tagged with omitempty
+// are left out if they are zero.
+func Marshal(in interface{}) (*Value, error) {
+	if in == nil {
+		return Null, nil
+	}
+	return marshal(reflect.ValueOf(in), "")
+}
+
+func marshal(rv reflect.Value, path string) (*Value, error) {
+
+	if rv.Type() == valueType {
+		if rv.IsNil() {
+			return Null, nil
+		}
+		return rv.Interface().(*Value), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return Null, nil
+		}
+		return marshal(rv.Elem(), path)
+
+	case reflect.Struct:
+		fields := structFields(rv.Type())
+		v := &Value{typ: HashType, hash: make(HashEntries, 0, len(fields))}
+		for _, f := range fields {
+			fv := rv.Field(f.index)
+			if f.omitEmpty && fv.IsZero() {
+				continue
+			}
+			x, err := marshal(fv, keyPath(path, f.key))
+			if err != nil {
+				return nil, err
+			}
+			v.hash = append(v.hash, HashEntry{Key: f.key, Value: x})
+		}
+		sort.SliceStable(v.hash, func(i, j int) bool {
+			return v.hash[i].Key < v.hash[j].Key
+		})
+		return v, nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, pathError(path, "unsupported type %s", rv.Type())
+		}
+		if rv.IsNil() {
+			return Null, nil
+		}
+		v := &Value{typ: HashType, hash: make(HashEntries, 0, rv.Len())}
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			x, err := marshal(iter.Value(), keyPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			v.hash = append(v.hash, HashEntry{Key: key, Value: x})
+		}
+		sort.Slice(v.hash, func(i, j int) bool {
+			return v.hash[i].Key < v.hash[j].Key
+		})
+		return v, nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return Null, nil
+		}
+		v := &Value{typ: ArrayType, array: make([]*Value, rv.Len())}
+		for i := range v.array {
+			x, err := marshal(rv.Index(i), indexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			v.array[i] = x
+		}
+		return v, nil
+
+	case reflect.String:
+		return NewString(rv.String()), nil
+
+	case reflect.Bool:
+		var i int64
+		if rv.Bool() {
+			i = 1
+		}
+		return NewInteger(i), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInteger(rv.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, pathError(path, "%d out of range for %s", u, IntegerType)
+		}
+		return NewInteger(int64(u)), nil
+
+	case reflect.Float32, reflect.Float64:
+		d := rv.Float()
+		if math.IsNaN(d) || math.IsInf(d, 0) {
+			return nil, pathError(path, "unsupported double: %g", d)
+		}
+		return NewDouble(d), nil
+	}
+
+	return nil, pathError(path, "unsupported type %s", rv.Type())
+}
@@ -0,0 +1,281 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// jsonValue parses a Value from JSON.
+func jsonValue(t *testing.T, s string) *Value {
+	t.Helper()
+	var v Value
+	if err := v.UnmarshalJSON([]byte(s)); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return &v
+}
+
+// jsonString formats a Value as compact JSON.
+func jsonString(t *testing.T, v *Value) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshaling JSON failed: %v", err)
+	}
+	return string(data)
+}
+
+type testFile struct {
+	Name   string `ggpack:"filename"`
+	Offset int64  `ggpack:"offset"`
+	Size   int32  `ggpack:"size"`
+	Flags  uint8  `ggpack:"flags,omitempty"`
+	Hidden bool   `ggpack:"hidden,omitempty"`
+	Skip   string `ggpack:"-"`
+	Loose  string
+	secret string
+}
+
+type testIndex struct {
+	Files []testFile `ggpack:"files"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	v := jsonValue(t, `{
+		"files": [
+			{"filename": "a.txt", "offset": 10, "size": 5, "flags": 255,
+			 "hidden": 1, "Skip": "x", "LOOSE": "folded",
+			 "secret": "s", "unknown": [1, 2]},
+			{"filename": "b.txt", "offset": null}
+		]
+	}`)
+	var got testIndex
+	if err := Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := testIndex{Files: []testFile{
+		{Name: "a.txt", Offset: 10, Size: 5, Flags: 255, Hidden: true, Loose: "folded"},
+		{Name: "b.txt"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalPrefersExactKeys(t *testing.T) {
+	var got struct {
+		Lower string `ggpack:"a"`
+		Upper string `ggpack:"A"`
+	}
+	if err := Unmarshal(jsonValue(t, `{"a": "lower", "A": "upper"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Lower != "lower" || got.Upper != "upper" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestUnmarshalTargets(t *testing.T) {
+	v := jsonValue(t, `{
+		"ptr": {"filename": "p"},
+		"ptrs": [{"size": 1}, null],
+		"ints": [1, -2],
+		"array": [1, 2],
+		"map": {"x": 1.5, "y": 2},
+		"any": {"a": [1, 2.5, "s", null]},
+		"raw": {"k": "v"},
+		"double": 3
+	}`)
+	var got struct {
+		Ptr    *testFile          `ggpack:"ptr"`
+		Ptrs   []*testFile        `ggpack:"ptrs"`
+		Ints   []int              `ggpack:"ints"`
+		Array  [3]int16           `ggpack:"array"`
+		Map    map[string]float32 `ggpack:"map"`
+		Any    interface{}        `ggpack:"any"`
+		Raw    *Value             `ggpack:"raw"`
+		Double float64            `ggpack:"double"`
+	}
+	got.Array[2] = 7
+	if err := Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Ptr == nil || got.Ptr.Name != "p" {
+		t.Errorf("ptr = %+v", got.Ptr)
+	}
+	if len(got.Ptrs) != 2 || got.Ptrs[0] == nil || got.Ptrs[0].Size != 1 || got.Ptrs[1] != nil {
+		t.Errorf("ptrs = %+v", got.Ptrs)
+	}
+	if !reflect.DeepEqual(got.Ints, []int{1, -2}) {
+		t.Errorf("ints = %v", got.Ints)
+	}
+	if got.Array != [3]int16{1, 2, 0} {
+		t.Errorf("array = %v", got.Array)
+	}
+	if !reflect.DeepEqual(got.Map, map[string]float32{"x": 1.5, "y": 2}) {
+		t.Errorf("map = %v", got.Map)
+	}
+	wantAny := map[string]interface{}{
+		"a": []interface{}{int64(1), 2.5, "s", nil},
+	}
+	if !reflect.DeepEqual(got.Any, wantAny) {
+		t.Errorf("any = %#v", got.Any)
+	}
+	if got.Raw != v.Find("raw") {
+		t.Errorf("raw is not the value itself")
+	}
+	if got.Double != 3 {
+		t.Errorf("double = %g", got.Double)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		json string
+		out  interface{}
+		want string
+	}{
+		{`{"files": [{"size": "5"}]}`, &testIndex{},
+			"files[0].size: expected integer, got string"},
+		{`{"files": [{}, {"filename": 1}]}`, &testIndex{},
+			"files[1].filename: expected string, got integer"},
+		{`{"files": {}}`, &testIndex{},
+			"files: expected array, got hash"},
+		{`{"files": [{"size": 2147483648}]}`, &testIndex{},
+			"files[0].size: 2147483648 out of range for int32"},
+		{`{"files": [{"flags": 256}]}`, &testIndex{},
+			"files[0].flags: 256 out of range for uint8"},
+		{`{"files": [{"flags": -1}]}`, &testIndex{},
+			"files[0].flags: -1 out of range for uint8"},
+		{`{"files": [{"hidden": 1.0}]}`, &testIndex{},
+			"files[0].hidden: expected integer, got double"},
+		{`{"x": 1e300}`, &struct{ X float32 }{},
+			"x: 1e+300 out of range for float32"},
+		{`{"x": "1"}`, &struct{ X float64 }{},
+			"x: expected double, got string"},
+		{`{"x": [1, 2, 3]}`, &struct{ X [2]int }{},
+			"x: 3 elements do not fit into [2]int"},
+		{`{"x": {"a": 1}}`, &struct{ X map[int]int }{},
+			"x: unsupported type map[int]int"},
+		{`{"x": 1}`, &struct{ X chan int }{},
+			"x: unsupported type chan int"},
+		{`[]`, &testIndex{},
+			"expected hash, got array"},
+		{`{}`, testIndex{},
+			"unmarshal needs a non-nil pointer, got ggpack.testIndex"},
+		{`{}`, (*testIndex)(nil),
+			"unmarshal needs a non-nil pointer, got *ggpack.testIndex"},
+	} {
+		err := Unmarshal(jsonValue(t, tc.json), tc.out)
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tc.json, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("%s: error %q, want %q", tc.json, got, tc.want)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	type inner struct {
+		A int `ggpack:"a"`
+	}
+	for _, tc := range []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"nil", nil, `null`},
+		{"struct with tags",
+			testFile{Name: "a.txt", Offset: 10, Size: 5, Skip: "x", Loose: "l", secret: "s"},
+			`{"Loose":"l","filename":"a.txt","offset":10,"size":5}`},
+		{"omitempty",
+			testFile{Flags: 2, Hidden: true},
+			`{"Loose":"","filename":"","flags":2,"hidden":1,"offset":0,"size":0}`},
+		{"pointers",
+			struct {
+				P *inner
+				N *inner
+			}{P: &inner{A: 1}},
+			`{"N":null,"P":{"a":1}}`},
+		{"slices and arrays",
+			struct {
+				S []string
+				N []int
+				A [2]float64
+				E []bool
+			}{S: []string{"x"}, A: [2]float64{0.5, -1}, E: []bool{}},
+			`{"A":[0.5,-1.0],"E":[],"N":null,"S":["x"]}`},
+		{"maps",
+			map[string]interface{}{"b": uint16(2), "a": "x", "c": nil},
+			`{"a":"x","b":2,"c":null}`},
+		{"value", struct{ V *Value }{V: NewString("v")}, `{"V":"v"}`},
+	} {
+		v, err := Marshal(tc.in)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got := jsonString(t, v); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"uint64 overflow",
+			struct{ X []uint64 }{X: []uint64{1, math.MaxUint64}},
+			"X[1]: 18446744073709551615 out of range for integer"},
+		{"NaN", map[string]float64{"x": math.NaN()},
+			"x: unsupported double: NaN"},
+		{"infinity", struct{ F float32 }{F: float32(math.Inf(1))},
+			"F: unsupported double: +Inf"},
+		{"map key", map[int]string{1: "x"},
+			"unsupported type map[int]string"},
+		{"func", struct{ F func() }{F: func() {}},
+			"F: unsupported type func()"},
+	} {
+		_, err := Marshal(tc.in)
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tc.name, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("%s: error %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	in := testIndex{Files: []testFile{
+		{Name: "a.txt", Offset: 1 << 40, Size: -1, Flags: 255, Hidden: true},
+		{Name: "b.txt"},
+	}}
+	v, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out testIndex
+	if err := Unmarshal(v, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+}
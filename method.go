@@ -0,0 +1,53 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import "fmt"
+
+// Method is the variant of the XOR encoding used in a ggpack container.
+// The numbers are the ones used by engge and NGGPack. Neither of them
+// records which release of the game uses which variant so the names
+// describe the encoding. Only three variants can be told apart:
+// engge's method 1 encodes exactly like 3. It is accepted as input
+// but treated as MethodDefault.
+type Method int
+
+const (
+	// MethodPlain uses the key 0x6d without the 0x0d pass.
+	MethodPlain Method = 0
+	// MethodAltKey uses the key 0xad with the 0x0d pass.
+	MethodAltKey Method = 2
+	// MethodDefault uses the key 0x6d with the 0x0d pass.
+	// It is the first method tried by ReadPack.
+	MethodDefault Method = 3
+)
+
+func (m Method) valid() bool {
+	return m >= MethodPlain && m <= MethodDefault
+}
+
+// canonical maps method 1 to MethodDefault which encodes the same.
+func (m Method) canonical() Method {
+	if m == 1 {
+		return MethodDefault
+	}
+	return m
+}
+
+func (m Method) String() string {
+	switch m.canonical() {
+	case MethodPlain:
+		return "plain (0)"
+	case MethodAltKey:
+		return "altkey (2)"
+	case MethodDefault:
+		return "default (3)"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(m))
+	}
+}
@@ -0,0 +1,19 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+//go:build !linux
+// +build !linux
+
+package ggpack
+
+import "os"
+
+// mapFile does not map the file on other systems than Linux.
+// The file is read with ReadAt instead.
+func mapFile(*os.File) ([]byte, error) { return nil, nil }
+
+func unmapFile([]byte) error { return nil }
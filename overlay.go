@@ -0,0 +1,108 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+)
+
+// Overlay layers the files of several Readers into one namespace.
+// Files in later Readers override files with the same name in
+// earlier ones. Like in the game names are compared case-insensitively.
+type Overlay struct {
+	readers []*Reader
+	files   []OverlayEntry
+	lookup  map[string]int
+}
+
+// OverlayEntry is a file of an Overlay.
+type OverlayEntry struct {
+	Entry
+	// Pack is the index of the Reader the file is stored in.
+	Pack int
+}
+
+// NewOverlay creates an Overlay over the given Readers in the
+// order of increasing precedence. ReadPack has to be called
+// on the Readers before.
+func NewOverlay(readers ...*Reader) (*Overlay, error) {
+
+	o := &Overlay{
+		readers: readers,
+		lookup:  make(map[string]int),
+	}
+
+	for pack, r := range readers {
+		files, err := r.Files()
+		if err != nil {
+			return nil, fmt.Errorf("pack %d: %v", pack, err)
+		}
+		for i := range files {
+			entry := OverlayEntry{Entry: files[i], Pack: pack}
+			key := strings.ToLower(entry.Name)
+			// Overridden files keep their position.
+			if idx, found := o.lookup[key]; found {
+				o.files[idx] = entry
+				continue
+			}
+			o.lookup[key] = len(o.files)
+			o.files = append(o.files, entry)
+		}
+	}
+
+	return o, nil
+}
+
+// Readers returns the Readers of the overlay.
+func (o *Overlay) Readers() []*Reader {
+	return o.readers
+}
+
+// Files returns the merged files of the overlay. The files are
+// in the order they first appear in the Readers.
+// The returned slice must not be modified.
+func (o *Overlay) Files() []OverlayEntry {
+	return o.files
+}
+
+// Lookup finds the file with the given name. If the file does not
+// exist the returned error wraps fs.ErrNotExist.
+func (o *Overlay) Lookup(name string) (*OverlayEntry, error) {
+	idx, ok := o.lookup[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return &o.files[idx], nil
+}
+
+// OpenEntry returns a reader which decodes the content of the
+// given file from the Reader it is stored in.
+func (o *Overlay) OpenEntry(e *OverlayEntry) (ReadSeekerAt, error) {
+	if e.Pack < 0 || e.Pack >= len(o.readers) {
+		return nil, fmt.Errorf("%s: invalid pack %d", e.Name, e.Pack)
+	}
+	return o.readers[e.Pack].OpenEntry(&e.Entry)
+}
+
+// Open returns the decoded content of the file with the given name.
+// See Reader.Open for details.
+func (o *Overlay) Open(name string) (io.ReadCloser, error) {
+	e, err := o.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := o.OpenEntry(e)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(rd), nil
+}
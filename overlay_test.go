@@ -0,0 +1,105 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	r0 := writePack(t, MethodDefault, map[string][]byte{
+		"Shared.txt": []byte("first"),
+		"a.txt":      []byte("a"),
+		"b.txt":      []byte("b"),
+	})
+	r1 := writePack(t, MethodAltKey, map[string][]byte{
+		"c.txt":      []byte("c"),
+		"shared.txt": []byte("second"),
+	})
+	r2 := writePack(t, MethodPlain, map[string][]byte{
+		"SHARED.TXT": []byte("third"),
+		"d.txt":      []byte("d"),
+	})
+
+	base, err := NewOverlay(r0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOverlay(r0, r1, r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(o.Files()); n != 5 {
+		t.Errorf("%d files, want 5", n)
+	}
+	if len(o.Readers()) != 3 {
+		t.Errorf("%d readers, want 3", len(o.Readers()))
+	}
+
+	for _, tc := range []struct {
+		name    string
+		pack    int
+		content string
+	}{
+		{"shared.txt", 2, "third"},
+		{"Shared.txt", 2, "third"},
+		{"a.txt", 0, "a"},
+		{"B.TXT", 0, "b"},
+		{"c.txt", 1, "c"},
+		{"d.txt", 2, "d"},
+	} {
+		e, err := o.Lookup(tc.name)
+		if err != nil {
+			t.Errorf("looking up %s failed: %v", tc.name, err)
+			continue
+		}
+		if e.Pack != tc.pack {
+			t.Errorf("%s: found in pack %d, want %d", tc.name, e.Pack, tc.pack)
+		}
+		rd, err := o.Open(tc.name)
+		if err != nil {
+			t.Errorf("opening %s failed: %v", tc.name, err)
+			continue
+		}
+		data, err := ioutil.ReadAll(rd)
+		rd.Close()
+		if err != nil {
+			t.Errorf("reading %s failed: %v", tc.name, err)
+			continue
+		}
+		if string(data) != tc.content {
+			t.Errorf("%s: content %q, want %q", tc.name, data, tc.content)
+		}
+	}
+
+	// The overriding file keeps the position of the first one
+	// but carries the name it has in its own pack.
+	for i, e := range base.Files() {
+		if e.Name != "Shared.txt" {
+			continue
+		}
+		if got := o.Files()[i]; got.Name != "SHARED.TXT" || got.Pack != 2 {
+			t.Errorf("file %d is %s from pack %d, want SHARED.TXT from pack 2",
+				i, got.Name, got.Pack)
+		}
+	}
+
+	if _, err := o.Lookup("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("looking up missing file: %v", err)
+	}
+	if _, err := o.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("opening missing file: %v", err)
+	}
+	if _, err := o.OpenEntry(&OverlayEntry{Pack: 3}); err == nil {
+		t.Error("no error for invalid pack")
+	}
+}
@@ -0,0 +1,221 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Match is a value found by Query.
+type Match struct {
+	// Path is the path of the value in the notation of the
+	// errors of Unmarshal, e.g. "objects[3].name".
+	// It is empty for the queried value itself.
+	Path  string
+	Value *Value
+}
+
+type queryStepKind int
+
+const (
+	queryKey queryStepKind = iota
+	queryIndex
+	queryWildcard
+)
+
+type queryStep struct {
+	kind      queryStepKind
+	key       string
+	index     int
+	recursive bool
+}
+
+// Query returns the values matching the given expression
+// in document order. The syntax is a subset of JSONPath:
+//
+//	$            the queried value (optional)
+//	.name        the value stored under the key name in a hash
+//	['name']     the same for keys with special characters
+//	[n]          the n-th element of an array, counted from the end if negative
+//	[*] or .*    all elements of an array or all values of a hash
+//	..name       name looked up in the value and all its descendants
+//
+// The leading dot may be omitted, e.g. "objects[*].name".
+// Steps which do not apply to a value do not match.
+func (v *Value) Query(expr string) ([]Match, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	matches := []Match{{Value: v}}
+	for _, step := range steps {
+		if step.recursive {
+			matches = descendants(matches)
+		}
+		matches = step.apply(matches)
+	}
+	return matches, nil
+}
+
+func parseQuery(expr string) ([]queryStep, error) {
+
+	var steps []queryStep
+
+	pos := 0
+	if strings.HasPrefix(expr, "$") {
+		pos++
+	}
+
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("query %q: %d: %s", expr, pos, fmt.Sprintf(format, args...))
+	}
+
+	name := func() string {
+		start := pos
+		for pos < len(expr) && expr[pos] != '.' && expr[pos] != '[' {
+			pos++
+		}
+		return expr[start:pos]
+	}
+
+	for pos < len(expr) {
+		var step queryStep
+
+		switch {
+		case expr[pos] == '.' || (pos == 0 && expr[pos] != '['):
+			if expr[pos] == '.' {
+				pos++
+				if pos < len(expr) && expr[pos] == '.' {
+					step.recursive = true
+					pos++
+				}
+			}
+			if step.recursive && pos < len(expr) && expr[pos] == '[' {
+				break
+			}
+			switch key := name(); key {
+			case "":
+				return nil, fail("missing key")
+			case "*":
+				step.kind = queryWildcard
+			default:
+				step.kind, step.key = queryKey, key
+			}
+			steps = append(steps, step)
+			continue
+		case expr[pos] != '[':
+			return nil, fail("unexpected %q", expr[pos])
+		}
+
+		// Bracket notation
+		pos++
+		end := strings.IndexByte(expr[pos:], ']')
+		if end < 0 {
+			return nil, fail("missing ']'")
+		}
+		inner := expr[pos : pos+end]
+		switch {
+		case inner == "*":
+			step.kind = queryWildcard
+		case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') &&
+			inner[len(inner)-1] == inner[0]:
+			step.kind, step.key = queryKey, inner[1:len(inner)-1]
+		default:
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fail("invalid index %q", inner)
+			}
+			step.kind, step.index = queryIndex, idx
+		}
+		pos += end + 1
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// descendants returns the matches and all values below them
+// in document order.
+func descendants(matches []Match) []Match {
+	var all []Match
+	var walk func(Match)
+	walk = func(m Match) {
+		all = append(all, m)
+		if m.Value == nil {
+			return
+		}
+		switch m.Value.typ {
+		case HashType:
+			for _, e := range m.Value.hash {
+				walk(Match{Path: keyPath(m.Path, e.Key), Value: e.Value})
+			}
+		case ArrayType:
+			for i, e := range m.Value.array {
+				walk(Match{Path: indexPath(m.Path, i), Value: e})
+			}
+		}
+	}
+	for _, m := range matches {
+		walk(m)
+	}
+	return all
+}
+
+func (step *queryStep) apply(matches []Match) []Match {
+	var next []Match
+	for _, m := range matches {
+		v := m.Value
+		if v == nil {
+			continue
+		}
+		switch step.kind {
+		case queryKey:
+			if v.typ != HashType {
+				continue
+			}
+			idx := sort.Search(len(v.hash), func(i int) bool {
+				return v.hash[i].Key >= step.key
+			})
+			if idx < len(v.hash) && v.hash[idx].Key == step.key {
+				next = append(next, Match{
+					Path:  keyPath(m.Path, step.key),
+					Value: v.hash[idx].Value,
+				})
+			}
+		case queryIndex:
+			if v.typ != ArrayType {
+				continue
+			}
+			idx := step.index
+			if idx < 0 {
+				idx += len(v.array)
+			}
+			if idx >= 0 && idx < len(v.array) {
+				next = append(next, Match{
+					Path:  indexPath(m.Path, idx),
+					Value: v.array[idx],
+				})
+			}
+		case queryWildcard:
+			switch v.typ {
+			case HashType:
+				for _, e := range v.hash {
+					next = append(next, Match{Path: keyPath(m.Path, e.Key), Value: e.Value})
+				}
+			case ArrayType:
+				for i, e := range v.array {
+					next = append(next, Match{Path: indexPath(m.Path, i), Value: e})
+				}
+			}
+		}
+	}
+	return next
+}
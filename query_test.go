@@ -0,0 +1,120 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"strings"
+	"testing"
+)
+
+const queryDoc = `{
+	"name": "Bank",
+	"objects": [
+		{"name": "door", "pos": "{1,2}"},
+		{"name": "window", "anim": {"name": "open"}}
+	],
+	"z": [1, 2, 3],
+	"a.b": {"c": 1},
+	"layers": []
+}`
+
+func TestQuery(t *testing.T) {
+	doc := jsonValue(t, queryDoc)
+
+	for _, tc := range []struct {
+		expr string
+		// want are the matches as "path=value".
+		want []string
+	}{
+		{"", []string{"=" + jsonString(t, doc)}},
+		{"$", []string{"=" + jsonString(t, doc)}},
+		{"name", []string{`name="Bank"`}},
+		{"$.name", []string{`name="Bank"`}},
+		{".name", []string{`name="Bank"`}},
+		{"$['name']", []string{`name="Bank"`}},
+		{"missing", nil},
+		{"name.x", nil},
+		{"objects.name", nil},
+		{"objects[*].name", []string{
+			`objects[0].name="door"`,
+			`objects[1].name="window"`,
+		}},
+		{"objects.*.pos", []string{`objects[0].pos="{1,2}"`}},
+		{"$..name", []string{
+			`name="Bank"`,
+			`objects[0].name="door"`,
+			`objects[1].name="window"`,
+			`objects[1].anim.name="open"`,
+		}},
+		{"objects..name", []string{
+			`objects[0].name="door"`,
+			`objects[1].name="window"`,
+			`objects[1].anim.name="open"`,
+		}},
+		{"$..[0]", []string{`objects[0]={"name":"door","pos":"{1,2}"}`, `z[0]=1`}},
+		{"z[0]", []string{"z[0]=1"}},
+		{"z[-1]", []string{"z[2]=3"}},
+		{"z[-3]", []string{"z[0]=1"}},
+		{"z[3]", nil},
+		{"z[-4]", nil},
+		{"z[*]", []string{"z[0]=1", "z[1]=2", "z[2]=3"}},
+		{"z.*", []string{"z[0]=1", "z[1]=2", "z[2]=3"}},
+		{"layers[*]", nil},
+		{"name[0]", nil},
+		{"['a.b'].c", []string{"a.b.c=1"}},
+		{`["a.b"]`, []string{`a.b={"c":1}`}},
+		{"$.*", []string{
+			`a.b={"c":1}`,
+			`layers=[]`,
+			`name="Bank"`,
+			`objects=` + jsonString(t, doc.Find("objects")),
+			`z=[1,2,3]`,
+		}},
+	} {
+		matches, err := doc.Query(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tc.expr, err)
+			continue
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.Path+"="+jsonString(t, m.Value))
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%q: got\n%s\nwant\n%s", tc.expr,
+				strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	doc := jsonValue(t, queryDoc)
+
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"$.", `query "$.": 2: missing key`},
+		{"name.", `query "name.": 5: missing key`},
+		{"a..", `query "a..": 3: missing key`},
+		{"z[1", `query "z[1": 2: missing ']'`},
+		{"z[x]", `query "z[x]": 2: invalid index "x"`},
+		{"z[]", `query "z[]": 2: invalid index ""`},
+		{"z['a]", `query "z['a]": 2: invalid index "'a"`},
+		{"[0]x", `query "[0]x": 3: unexpected 'x'`},
+	} {
+		_, err := doc.Query(tc.expr)
+		if err == nil {
+			t.Errorf("%q: no error, want %q", tc.expr, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("%q: error %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package room
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Point is a position in room coordinates, written as "{x,y}".
+type Point struct {
+	X, Y int
+}
+
+// Vec is a pair of factors like a parallax, written as "{x,y}".
+type Vec struct {
+	X, Y float64
+}
+
+// Rect is a rectangle, written as "{{x1,y1},{x2,y2}}".
+type Rect struct {
+	Min, Max Point
+}
+
+// Polygon is a closed polygon, written as "{x1,y1};{x2,y2};...".
+type Polygon []Point
+
+func (p Point) String() string { return fmt.Sprintf("{%d,%d}", p.X, p.Y) }
+
+func (v Vec) String() string {
+	return "{" + strconv.FormatFloat(v.X, 'g', -1, 64) + "," +
+		strconv.FormatFloat(v.Y, 'g', -1, 64) + "}"
+}
+
+func (r Rect) String() string { return "{" + r.Min.String() + "," + r.Max.String() + "}" }
+
+func (p Polygon) String() string {
+	parts := make([]string, len(p))
+	for i, pt := range p {
+		parts[i] = pt.String()
+	}
+	return strings.Join(parts, ";")
+}
+
+// Contains reports if the point is inside the rectangle.
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+// Contains reports if the point is inside the polygon.
+func (p Polygon) Contains(pt Point) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) &&
+			float64(pt.X) < float64(b.X-a.X)*float64(pt.Y-a.Y)/float64(b.Y-a.Y)+float64(a.X) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// pair splits "{a,b}" into a and b.
+func pair(s string) (string, string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return "", "", false
+	}
+	s = s[1 : len(s)-1]
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// ParsePoint parses a point like "{10,20}".
+func ParsePoint(s string) (Point, error) {
+	if a, b, ok := pair(s); ok {
+		x, errX := strconv.Atoi(a)
+		y, errY := strconv.Atoi(b)
+		if errX == nil && errY == nil {
+			return Point{X: x, Y: y}, nil
+		}
+	}
+	return Point{}, fmt.Errorf("invalid point %q", s)
+}
+
+// ParseVec parses a pair of factors like "{0.5,1}".
+func ParseVec(s string) (Vec, error) {
+	if a, b, ok := pair(s); ok {
+		x, errX := strconv.ParseFloat(a, 64)
+		y, errY := strconv.ParseFloat(b, 64)
+		if errX == nil && errY == nil {
+			return Vec{X: x, Y: y}, nil
+		}
+	}
+	return Vec{}, fmt.Errorf("invalid vector %q", s)
+}
+
+// ParseRect parses a rectangle like "{{-10,0},{10,40}}".
+func ParseRect(s string) (Rect, error) {
+	if a, b, ok := pair(s); ok {
+		min, errMin := ParsePoint(a)
+		max, errMax := ParsePoint(b)
+		if errMin == nil && errMax == nil {
+			return Rect{Min: min, Max: max}, nil
+		}
+	}
+	return Rect{}, fmt.Errorf("invalid rectangle %q", s)
+}
+
+// ParsePolygon parses a polygon like "{0,0};{100,0};{100,50}".
+func ParsePolygon(s string) (Polygon, error) {
+	var poly Polygon
+	for _, part := range strings.Split(s, ";") {
+		p, err := ParsePoint(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid polygon %q", s)
+		}
+		poly = append(poly, p)
+	}
+	return poly, nil
+}
@@ -0,0 +1,157 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package room
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePoint(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Point
+		ok   bool
+	}{
+		{"{10,20}", Point{10, 20}, true},
+		{" { -5 , 7 } ", Point{-5, 7}, true},
+		{"{0,0}", Point{}, true},
+		{"", Point{}, false},
+		{"{}", Point{}, false},
+		{"{10}", Point{}, false},
+		{"10,20", Point{}, false},
+		{"{10,20", Point{}, false},
+		{"{1.5,2}", Point{}, false},
+		{"{a,b}", Point{}, false},
+		{"{{1,2},3}", Point{}, false},
+	} {
+		got, err := ParsePoint(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("ParsePoint(%q): err = %v, want ok = %t", tc.in, err, tc.ok)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParsePoint(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseRect(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Rect
+		ok   bool
+	}{
+		{"{{-10,0},{10,40}}", Rect{Point{-10, 0}, Point{10, 40}}, true},
+		{"{ {1,2} , {3,4} }", Rect{Point{1, 2}, Point{3, 4}}, true},
+		{"{{1,2}}", Rect{}, false},
+		{"{1,2}", Rect{}, false},
+		{"{{1,2},{3}}", Rect{}, false},
+		{"{{1,2},{3,4}", Rect{}, false},
+		{"", Rect{}, false},
+	} {
+		got, err := ParseRect(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("ParseRect(%q): err = %v, want ok = %t", tc.in, err, tc.ok)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseRect(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParsePolygon(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Polygon
+		ok   bool
+	}{
+		{"{0,0};{100,0};{100,50}", Polygon{{0, 0}, {100, 0}, {100, 50}}, true},
+		{"{1,2}", Polygon{{1, 2}}, true},
+		{"{0,0}; {1,1} ;{2,0}", Polygon{{0, 0}, {1, 1}, {2, 0}}, true},
+		{"", nil, false},
+		{"{0,0};", nil, false},
+		{"{0,0};;{1,1}", nil, false},
+		{"{0,0},{1,1}", nil, false},
+	} {
+		got, err := ParsePolygon(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("ParsePolygon(%q): err = %v, want ok = %t", tc.in, err, tc.ok)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParsePolygon(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGeometryString(t *testing.T) {
+	for _, tc := range []struct {
+		got  string
+		want string
+	}{
+		{Point{-1, 2}.String(), "{-1,2}"},
+		{Vec{0.5, 1}.String(), "{0.5,1}"},
+		{Rect{Point{1, 2}, Point{3, 4}}.String(), "{{1,2},{3,4}}"},
+		{Polygon{{0, 0}, {1, 2}}.String(), "{0,0};{1,2}"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	// An L shape with the notch in the upper right.
+	ell := Polygon{{0, 0}, {20, 0}, {20, 10}, {10, 10}, {10, 20}, {0, 20}}
+	triangle := Polygon{{0, 0}, {10, 0}, {5, 10}}
+
+	for _, tc := range []struct {
+		name string
+		poly Polygon
+		pt   Point
+		want bool
+	}{
+		{"square center", square, Point{5, 5}, true},
+		{"square outside left", square, Point{-1, 5}, false},
+		{"square outside right", square, Point{11, 5}, false},
+		{"square outside above", square, Point{5, 11}, false},
+		{"square outside below", square, Point{5, -1}, false},
+		{"ell lower arm", ell, Point{15, 5}, true},
+		{"ell upper arm", ell, Point{5, 15}, true},
+		{"ell notch", ell, Point{15, 15}, false},
+		{"triangle inside", triangle, Point{5, 3}, true},
+		{"triangle beside tip", triangle, Point{1, 8}, false},
+		{"empty", Polygon{}, Point{0, 0}, false},
+		{"single point", Polygon{{1, 1}}, Point{1, 1}, false},
+	} {
+		if got := tc.poly.Contains(tc.pt); got != tc.want {
+			t.Errorf("%s: Contains(%v) = %t, want %t", tc.name, tc.pt, got, tc.want)
+		}
+	}
+}
+
+func TestRectContains(t *testing.T) {
+	r := Rect{Point{-10, 0}, Point{10, 40}}
+	for _, tc := range []struct {
+		pt   Point
+		want bool
+	}{
+		{Point{0, 20}, true},
+		{Point{-10, 0}, true},
+		{Point{10, 40}, true},
+		{Point{-11, 20}, false},
+		{Point{0, 41}, false},
+	} {
+		if got := r.Contains(tc.pt); got != tc.want {
+			t.Errorf("Contains(%v) = %t, want %t", tc.pt, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+// Package room decodes the rooms (.wimpy) of the game.
+//
+// Rooms are dictionaries in the same format as the directory of
+// a ggpack. Geometry is stored as strings like "{10,20}" which
+// are decoded into Points, Rects and Polygons.
+package room
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+// Room is a decoded .wimpy file.
+type Room struct {
+	Name  string
+	Sheet string
+	// Background are the names of the background images.
+	// Wide rooms are composed of several images.
+	Background []string
+	Size       Point
+	Height     int
+	FullScreen int
+	Layers     []*Layer
+	Objects    []*Object
+	Walkboxes  []*Walkbox
+	Scalings   []*Scaling
+}
+
+// Layer is an image layer in front of or behind the background.
+type Layer struct {
+	Names    []string
+	Parallax Vec
+	ZSort    int
+}
+
+// Object is an object of a room.
+type Object struct {
+	Name       string
+	Parent     string
+	Pos        Point
+	UsePos     Point
+	UseDir     string
+	Hotspot    Rect
+	ZSort      int
+	Prop       bool
+	Spot       bool
+	Trigger    bool
+	Animations []*Animation
+}
+
+// Animation is an animation of an object.
+type Animation struct {
+	Name     string   `ggpack:"name"`
+	Frames   []string `ggpack:"frames"`
+	FPS      float64  `ggpack:"fps"`
+	Flags    int      `ggpack:"flags"`
+	Loop     bool     `ggpack:"loop"`
+	Triggers []string `ggpack:"triggers"`
+	// Layers are the sub animations drawn on top of each other.
+	Layers []*Animation `ggpack:"layers"`
+}
+
+// Walkbox is an area actors can walk in.
+type Walkbox struct {
+	Name    string
+	Polygon Polygon
+}
+
+// Scaling scales actors depending on their vertical position.
+// Without a trigger it is the default scaling of the room.
+type Scaling struct {
+	Trigger string
+	Values  []ScaleValue
+}
+
+// ScaleValue is the scale of actors at a vertical position,
+// written as "scale@y".
+type ScaleValue struct {
+	Scale float64
+	Y     int
+}
+
+// The raw structures as stored in the dictionary.
+type (
+	rawRoom struct {
+		Name       string          `ggpack:"name"`
+		Sheet      string          `ggpack:"sheet"`
+		Background *ggpack.Value   `ggpack:"background"`
+		RoomSize   string          `ggpack:"roomsize"`
+		Height     int             `ggpack:"height"`
+		FullScreen int             `ggpack:"fullscreen"`
+		Layers     []rawLayer      `ggpack:"layers"`
+		Objects    []rawObject     `ggpack:"objects"`
+		Walkboxes  []rawWalkbox    `ggpack:"walkboxes"`
+		Scaling    []*ggpack.Value `ggpack:"scaling"`
+	}
+
+	rawLayer struct {
+		Name     *ggpack.Value `ggpack:"name"`
+		Parallax *ggpack.Value `ggpack:"parallax"`
+		ZSort    int           `ggpack:"zsort"`
+	}
+
+	rawObject struct {
+		Name       string       `ggpack:"name"`
+		Parent     string       `ggpack:"parent"`
+		Pos        string       `ggpack:"pos"`
+		UsePos     string       `ggpack:"usepos"`
+		UseDir     string       `ggpack:"usedir"`
+		Hotspot    string       `ggpack:"hotspot"`
+		ZSort      int          `ggpack:"zsort"`
+		Prop       bool         `ggpack:"prop"`
+		Spot       bool         `ggpack:"spot"`
+		Trigger    bool         `ggpack:"trigger"`
+		Animations []*Animation `ggpack:"animations"`
+	}
+
+	rawWalkbox struct {
+		Name    string `ggpack:"name"`
+		Polygon string `ggpack:"polygon"`
+	}
+
+	rawScaling struct {
+		Trigger string   `ggpack:"trigger"`
+		Scaling []string `ggpack:"scaling"`
+	}
+)
+
+func errorAt(path string, err error) error {
+	return fmt.Errorf("%s: %v", path, err)
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func keyPath(path string, i int, key string) string {
+	return indexPath(path, i) + "." + key
+}
+
+// str returns the string of a value. Null is the empty string.
+func str(v *ggpack.Value, path string) (string, error) {
+	switch {
+	case v == nil || v.Type() == ggpack.NullType:
+		return "", nil
+	case v.Type() != ggpack.StringType:
+		return "", fmt.Errorf("%s: expected string, got %s", path, v.Type())
+	}
+	return v.String(), nil
+}
+
+// Parse decodes a room from the content of a .wimpy file.
+func Parse(data []byte) (*Room, error) {
+	v, err := ggpack.DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(v)
+}
+
+// Decode decodes a room from its dictionary. Keys not known are
+// ignored, values of unexpected types or with invalid geometry
+// are errors carrying their path like "objects[3].hotspot".
+func Decode(v *ggpack.Value) (*Room, error) {
+	var raw rawRoom
+	if err := ggpack.Unmarshal(v, &raw); err != nil {
+		return nil, err
+	}
+
+	r := &Room{
+		Name:       raw.Name,
+		Sheet:      raw.Sheet,
+		Height:     raw.Height,
+		FullScreen: raw.FullScreen,
+	}
+
+	var err error
+	if r.Background, err = names(raw.Background, "background"); err != nil {
+		return nil, err
+	}
+	if raw.RoomSize != "" {
+		if r.Size, err = ParsePoint(raw.RoomSize); err != nil {
+			return nil, errorAt("roomsize", err)
+		}
+	}
+
+	for i := range raw.Layers {
+		if r.Layers, err = appendLayer(r.Layers, &raw.Layers[i], i); err != nil {
+			return nil, err
+		}
+	}
+	for i := range raw.Objects {
+		if r.Objects, err = appendObject(r.Objects, &raw.Objects[i], i); err != nil {
+			return nil, err
+		}
+	}
+	for i := range raw.Walkboxes {
+		wb := &raw.Walkboxes[i]
+		poly, err := ParsePolygon(wb.Polygon)
+		if err != nil {
+			return nil, errorAt(keyPath("walkboxes", i, "polygon"), err)
+		}
+		r.Walkboxes = append(r.Walkboxes, &Walkbox{Name: wb.Name, Polygon: poly})
+	}
+	if r.Scalings, err = scalings(raw.Scaling); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// names decodes a name or an array of names.
+func names(v *ggpack.Value, path string) ([]string, error) {
+	if v == nil || v.Type() != ggpack.ArrayType {
+		name, err := str(v, path)
+		if err != nil || name == "" {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+	ns := make([]string, len(v.Array()))
+	for i, e := range v.Array() {
+		var err error
+		if ns[i], err = str(e, indexPath(path, i)); err != nil {
+			return nil, err
+		}
+	}
+	return ns, nil
+}
+
+func appendLayer(layers []*Layer, raw *rawLayer, i int) ([]*Layer, error) {
+	l := &Layer{ZSort: raw.ZSort, Parallax: Vec{X: 1, Y: 1}}
+	var err error
+	if l.Names, err = names(raw.Name, keyPath("layers", i, "name")); err != nil {
+		return nil, err
+	}
+	if p := raw.Parallax; p != nil {
+		switch p.Type() {
+		case ggpack.StringType:
+			if l.Parallax, err = ParseVec(p.String()); err != nil {
+				return nil, errorAt(keyPath("layers", i, "parallax"), err)
+			}
+		case ggpack.DoubleType:
+			l.Parallax.X = p.Double()
+		case ggpack.IntegerType:
+			l.Parallax.X = float64(p.Integer())
+		case ggpack.NullType:
+		default:
+			return nil, errorAt(keyPath("layers", i, "parallax"),
+				fmt.Errorf("expected string or number, got %s", p.Type()))
+		}
+	}
+	return append(layers, l), nil
+}
+
+func appendObject(objects []*Object, raw *rawObject, i int) ([]*Object, error) {
+	o := &Object{
+		Name:       raw.Name,
+		Parent:     raw.Parent,
+		UseDir:     raw.UseDir,
+		ZSort:      raw.ZSort,
+		Prop:       raw.Prop,
+		Spot:       raw.Spot,
+		Trigger:    raw.Trigger,
+		Animations: raw.Animations,
+	}
+	var err error
+	for _, p := range []struct {
+		key string
+		s   string
+		pt  *Point
+	}{
+		{"pos", raw.Pos, &o.Pos},
+		{"usepos", raw.UsePos, &o.UsePos},
+	} {
+		if p.s == "" {
+			continue
+		}
+		if *p.pt, err = ParsePoint(p.s); err != nil {
+			return nil, errorAt(keyPath("objects", i, p.key), err)
+		}
+	}
+	if raw.Hotspot != "" {
+		if o.Hotspot, err = ParseRect(raw.Hotspot); err != nil {
+			return nil, errorAt(keyPath("objects", i, "hotspot"), err)
+		}
+	}
+	return append(objects, o), nil
+}
+
+// scalings decodes the scaling of a room. It is either a list
+// of scale values or a list of hashes with a trigger and a
+// list of scale values.
+func scalings(raw []*ggpack.Value) ([]*Scaling, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if raw[0] == nil || raw[0].Type() != ggpack.HashType {
+		s, err := scaling("", raw, "scaling")
+		if err != nil {
+			return nil, err
+		}
+		return []*Scaling{s}, nil
+	}
+
+	ss := make([]*Scaling, len(raw))
+	for i, v := range raw {
+		path := indexPath("scaling", i)
+		if v == nil || v.Type() != ggpack.HashType {
+			return nil, fmt.Errorf("%s: expected hash, got %s", path, typeOf(v))
+		}
+		trigger, err := str(v.Find("trigger"), path+".trigger")
+		if err != nil {
+			return nil, err
+		}
+		values := v.Find("scaling")
+		if values != nil && values.Type() != ggpack.ArrayType && values.Type() != ggpack.NullType {
+			return nil, fmt.Errorf("%s.scaling: expected array, got %s", path, values.Type())
+		}
+		var elems []*ggpack.Value
+		if values != nil {
+			elems = values.Array()
+		}
+		if ss[i], err = scaling(trigger, elems, path+".scaling"); err != nil {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
+
+func typeOf(v *ggpack.Value) ggpack.ValueType {
+	if v == nil {
+		return ggpack.NullType
+	}
+	return v.Type()
+}
+
+// scaling decodes a list of scale values like "0.5@20".
+func scaling(trigger string, values []*ggpack.Value, path string) (*Scaling, error) {
+	s := &Scaling{Trigger: trigger}
+	for i, v := range values {
+		path := indexPath(path, i)
+		text, err := str(v, path)
+		if err != nil {
+			return nil, err
+		}
+		if at := strings.IndexByte(text, '@'); at >= 0 {
+			scale, errS := strconv.ParseFloat(strings.TrimSpace(text[:at]), 64)
+			y, errY := strconv.Atoi(strings.TrimSpace(text[at+1:]))
+			if errS == nil && errY == nil {
+				s.Values = append(s.Values, ScaleValue{Scale: scale, Y: y})
+				continue
+			}
+		}
+		return nil, fmt.Errorf("%s: invalid scale value %q", path, text)
+	}
+	return s, nil
+}
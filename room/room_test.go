@@ -0,0 +1,283 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package room
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/s-l-teichmann/ggpack"
+)
+
+func entry(key string, v *ggpack.Value) ggpack.HashEntry {
+	return ggpack.HashEntry{Key: key, Value: v}
+}
+
+func strs(ss ...string) *ggpack.Value {
+	v := ggpack.NewArray()
+	for _, s := range ss {
+		v.Append(ggpack.NewString(s))
+	}
+	return v
+}
+
+// parse encodes the hash as dictionary and parses it back as room.
+func parse(t *testing.T, v *ggpack.Value) (*Room, error) {
+	t.Helper()
+	data, err := ggpack.EncodeDict(v)
+	if err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+	return Parse(data)
+}
+
+// testRoom is a room with all the supported keys.
+func testRoom() *ggpack.Value {
+	str, num := ggpack.NewString, ggpack.NewInteger
+	return ggpack.NewHash(
+		entry("name", str("Bank")),
+		entry("sheet", str("BankSheet")),
+		entry("background", strs("Bank_1", "Bank_2")),
+		entry("roomsize", str("{640,180}")),
+		entry("height", num(180)),
+		entry("fullscreen", num(1)),
+		entry("unknown", str("ignored")),
+		entry("layers", ggpack.NewArray(
+			ggpack.NewHash(
+				entry("name", str("Front")),
+				entry("parallax", str("{1.5,1}")),
+				entry("zsort", num(-1))),
+			ggpack.NewHash(
+				entry("name", strs("Back_1", "Back_2")),
+				entry("parallax", ggpack.NewDouble(0.5))),
+			ggpack.NewHash(
+				entry("name", str("Sky")),
+				entry("parallax", num(2))),
+		)),
+		entry("objects", ggpack.NewArray(
+			ggpack.NewHash(
+				entry("name", str("door")),
+				entry("parent", str("wall")),
+				entry("pos", str("{100,20}")),
+				entry("usepos", str("{100,10}")),
+				entry("usedir", str("DIR_BACK")),
+				entry("hotspot", str("{{-10,0},{10,40}}")),
+				entry("zsort", num(50)),
+				entry("prop", num(1)),
+				entry("animations", ggpack.NewArray(
+					ggpack.NewHash(
+						entry("name", str("state0")),
+						entry("frames", strs("door_closed")),
+						entry("fps", num(10)),
+						entry("loop", num(1)),
+						entry("layers", ggpack.NewArray(
+							ggpack.NewHash(entry("name", str("glow"))))),
+					))),
+			),
+			ggpack.NewHash(
+				entry("name", str("exit")),
+				entry("trigger", num(1)),
+				entry("spot", num(1))),
+		)),
+		entry("walkboxes", ggpack.NewArray(
+			ggpack.NewHash(
+				entry("name", str("floor")),
+				entry("polygon", str("{0,0};{640,0};{640,40};{0,40}"))),
+		)),
+		entry("scaling", ggpack.NewArray(
+			ggpack.NewHash(
+				entry("scaling", strs("0.5@10", "1@100"))),
+			ggpack.NewHash(
+				entry("trigger", str("stairs")),
+				entry("scaling", strs("0.75 @ 20"))),
+		)),
+	)
+}
+
+func TestParse(t *testing.T) {
+	want := &Room{
+		Name:       "Bank",
+		Sheet:      "BankSheet",
+		Background: []string{"Bank_1", "Bank_2"},
+		Size:       Point{640, 180},
+		Height:     180,
+		FullScreen: 1,
+		Layers: []*Layer{
+			{Names: []string{"Front"}, Parallax: Vec{1.5, 1}, ZSort: -1},
+			{Names: []string{"Back_1", "Back_2"}, Parallax: Vec{0.5, 1}},
+			{Names: []string{"Sky"}, Parallax: Vec{2, 1}},
+		},
+		Objects: []*Object{{
+			Name:    "door",
+			Parent:  "wall",
+			Pos:     Point{100, 20},
+			UsePos:  Point{100, 10},
+			UseDir:  "DIR_BACK",
+			Hotspot: Rect{Point{-10, 0}, Point{10, 40}},
+			ZSort:   50,
+			Prop:    true,
+			Animations: []*Animation{{
+				Name:   "state0",
+				Frames: []string{"door_closed"},
+				FPS:    10,
+				Loop:   true,
+				Layers: []*Animation{{Name: "glow"}},
+			}},
+		}, {
+			Name:    "exit",
+			Spot:    true,
+			Trigger: true,
+		}},
+		Walkboxes: []*Walkbox{{
+			Name:    "floor",
+			Polygon: Polygon{{0, 0}, {640, 0}, {640, 40}, {0, 40}},
+		}},
+		Scalings: []*Scaling{
+			{Values: []ScaleValue{{0.5, 10}, {1, 100}}},
+			{Trigger: "stairs", Values: []ScaleValue{{0.75, 20}}},
+		},
+	}
+
+	got, err := parse(t, testRoom())
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseMinimal(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   *ggpack.Value
+		want *Room
+	}{
+		{"empty", ggpack.NewHash(), &Room{}},
+		{"single background",
+			ggpack.NewHash(entry("background", ggpack.NewString("Bank"))),
+			&Room{Background: []string{"Bank"}}},
+		{"null values",
+			ggpack.NewHash(
+				entry("background", ggpack.Null),
+				entry("layers", ggpack.NewArray(ggpack.NewHash(
+					entry("parallax", ggpack.Null))))),
+			&Room{Layers: []*Layer{{Parallax: Vec{1, 1}}}}},
+		{"flat scaling",
+			ggpack.NewHash(entry("scaling", strs("0.25@5"))),
+			&Room{Scalings: []*Scaling{{Values: []ScaleValue{{0.25, 5}}}}}},
+	} {
+		got, err := parse(t, tc.in)
+		if err != nil {
+			t.Errorf("%s: parse failed: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	str, num := ggpack.NewString, ggpack.NewInteger
+
+	// objects returns four valid objects with the key of the
+	// object at the given index replaced.
+	objects := func(i int, key string, v *ggpack.Value) *ggpack.Value {
+		objs := ggpack.NewArray()
+		for j := 0; j < 4; j++ {
+			o := ggpack.NewHash(
+				entry("name", str("obj")),
+				entry("pos", str("{1,2}")),
+				entry("hotspot", str("{{0,0},{1,1}}")))
+			if j == i {
+				o.Set(key, v)
+			}
+			objs.Append(o)
+		}
+		return ggpack.NewHash(entry("objects", objs))
+	}
+
+	for _, tc := range []struct {
+		name string
+		in   *ggpack.Value
+		want string
+	}{
+		{"hotspot", objects(3, "hotspot", str("{1,2}")),
+			`objects[3].hotspot: invalid rectangle "{1,2}"`},
+		{"pos", objects(0, "pos", str("{1}")),
+			`objects[0].pos: invalid point "{1}"`},
+		{"usepos", objects(2, "usepos", str("x")),
+			`objects[2].usepos: invalid point "x"`},
+		{"object type", objects(1, "zsort", str("high")),
+			`objects[1].zsort: expected integer, got string`},
+		{"roomsize",
+			ggpack.NewHash(entry("roomsize", str("{640;180}"))),
+			`roomsize: invalid point "{640;180}"`},
+		{"background",
+			ggpack.NewHash(entry("background", num(1))),
+			`background: expected string, got integer`},
+		{"background element",
+			ggpack.NewHash(entry("background", ggpack.NewArray(str("a"), num(2)))),
+			`background[1]: expected string, got integer`},
+		{"layer name",
+			ggpack.NewHash(entry("layers", ggpack.NewArray(
+				ggpack.NewHash(entry("name", str("a"))),
+				ggpack.NewHash(entry("name", ggpack.NewArray(num(1))))))),
+			`layers[1].name[0]: expected string, got integer`},
+		{"layer parallax",
+			ggpack.NewHash(entry("layers", ggpack.NewArray(
+				ggpack.NewHash(entry("parallax", str("{a,1}")))))),
+			`layers[0].parallax: invalid vector "{a,1}"`},
+		{"layer parallax type",
+			ggpack.NewHash(entry("layers", ggpack.NewArray(
+				ggpack.NewHash(entry("parallax", ggpack.NewArray()))))),
+			`layers[0].parallax: expected string or number, got array`},
+		{"walkbox",
+			ggpack.NewHash(entry("walkboxes", ggpack.NewArray(
+				ggpack.NewHash(entry("polygon", str("{0,0};{1,1}"))),
+				ggpack.NewHash(entry("polygon", str("{0,0};{1,1};")))))),
+			`walkboxes[1].polygon: invalid polygon "{0,0};{1,1};"`},
+		{"flat scaling",
+			ggpack.NewHash(entry("scaling", strs("0.5@10", "0.5"))),
+			`scaling[1]: invalid scale value "0.5"`},
+		{"scaling hash",
+			ggpack.NewHash(entry("scaling", ggpack.NewArray(
+				ggpack.NewHash(entry("scaling", strs("1@1"))),
+				str("1@2")))),
+			`scaling[1]: expected hash, got string`},
+		{"scaling trigger",
+			ggpack.NewHash(entry("scaling", ggpack.NewArray(
+				ggpack.NewHash(entry("trigger", num(1)))))),
+			`scaling[0].trigger: expected string, got integer`},
+		{"scaling values",
+			ggpack.NewHash(entry("scaling", ggpack.NewArray(
+				ggpack.NewHash(entry("scaling", str("1@1")))))),
+			`scaling[0].scaling: expected array, got string`},
+		{"scaling value",
+			ggpack.NewHash(entry("scaling", ggpack.NewArray(
+				ggpack.NewHash(),
+				ggpack.NewHash(entry("scaling", strs("1@1", "a@2")))))),
+			`scaling[1].scaling[1]: invalid scale value "a@2"`},
+	} {
+		_, err := parse(t, tc.in)
+		if err == nil {
+			t.Errorf("%s: no error, want %q", tc.name, tc.want)
+			continue
+		}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("%s: error %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseInvalidDict(t *testing.T) {
+	if _, err := Parse([]byte("not a dictionary")); err == nil {
+		t.Error("no error for invalid dictionary")
+	}
+}
@@ -0,0 +1,416 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+// Node is a node of the syntax tree.
+type Node interface {
+	// Pos returns the position of the first token of the node.
+	Pos() Pos
+}
+
+// Expr is an expression.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is a statement.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Script is the root of the syntax tree of a source file.
+type Script struct {
+	Stmts []Stmt
+}
+
+// Pos implements Node.
+func (s *Script) Pos() Pos { return Pos{Offset: 0, Line: 1, Column: 1} }
+
+// Expressions
+
+type (
+	// BadExpr is an expression which could not be parsed.
+	BadExpr struct {
+		From Pos
+	}
+
+	// Ident is an identifier. The keywords this, base, __LINE__ and
+	// __FILE__ are represented as identifiers, too.
+	Ident struct {
+		NamePos Pos
+		Name    string
+	}
+
+	// BasicLit is a literal of kind INT, FLOAT, STRING, CHAR,
+	// NULL, TRUE or FALSE. Value is the source text of numbers
+	// and the decoded content of strings and characters.
+	BasicLit struct {
+		ValuePos Pos
+		Kind     Token
+		Value    string
+	}
+
+	// RootExpr is a slot of the root table like ::print.
+	RootExpr struct {
+		DColon Pos
+		Name   *Ident
+	}
+
+	// ParenExpr is a parenthesized expression.
+	ParenExpr struct {
+		Lparen Pos
+		X      Expr
+	}
+
+	// SelectorExpr is a slot access like x.name.
+	SelectorExpr struct {
+		X   Expr
+		Sel *Ident
+	}
+
+	// IndexExpr is an index expression like x[i].
+	IndexExpr struct {
+		X     Expr
+		Index Expr
+	}
+
+	// CallExpr is a function call.
+	CallExpr struct {
+		Fun  Expr
+		Args []Expr
+	}
+
+	// UnaryExpr is a prefix operation including typeof, clone,
+	// delete, resume and the prefix increments and decrements.
+	UnaryExpr struct {
+		OpPos Pos
+		Op    Token
+		X     Expr
+	}
+
+	// PostfixExpr is a postfix increment or decrement.
+	PostfixExpr struct {
+		X  Expr
+		Op Token
+	}
+
+	// BinaryExpr is a binary operation including in and instanceof.
+	BinaryExpr struct {
+		X  Expr
+		Op Token
+		Y  Expr
+	}
+
+	// AssignExpr is an assignment including new slots (<-)
+	// and compound assignments.
+	AssignExpr struct {
+		Lhs Expr
+		Op  Token
+		Rhs Expr
+	}
+
+	// CondExpr is a conditional expression cond ? then : else.
+	CondExpr struct {
+		Cond Expr
+		Then Expr
+		Else Expr
+	}
+
+	// ArrayLit is an array like [1, 2, 3].
+	ArrayLit struct {
+		Lbrack Pos
+		Elems  []Expr
+	}
+
+	// TableLit is a table like { name = "x", ["key"] = 1 }.
+	TableLit struct {
+		Lbrace Pos
+		Slots  []*Slot
+	}
+
+	// FuncLit is a function. Body is a block for functions and
+	// an expression statement for lambdas like @(x) x * 2.
+	FuncLit struct {
+		Func    Pos
+		Lambda  bool
+		Params  []*Param
+		Varargs bool
+		Body    Stmt
+	}
+
+	// ClassLit is a class. Extends is nil without base class.
+	ClassLit struct {
+		Class   Pos
+		Extends Expr
+		Attrs   *TableLit
+		Members []*Slot
+	}
+)
+
+// Slot is a slot of a table or a member of a class. Key is an
+// identifier, a string for JSON style keys or an arbitrary
+// expression if Computed like in [expr] = value.
+type Slot struct {
+	Key      Expr
+	Computed bool
+	Static   bool
+	Attrs    *TableLit
+	Value    Expr
+}
+
+// Pos implements Node.
+func (s *Slot) Pos() Pos { return s.Key.Pos() }
+
+// Param is a parameter of a function. Default is nil
+// for parameters without default value.
+type Param struct {
+	Name    *Ident
+	Default Expr
+}
+
+// Pos implements Node.
+func (p *Param) Pos() Pos { return p.Name.Pos() }
+
+// Statements
+
+type (
+	// BadStmt is a statement which could not be parsed.
+	BadStmt struct {
+		From Pos
+	}
+
+	// EmptyStmt is a lone semicolon.
+	EmptyStmt struct {
+		Semicolon Pos
+	}
+
+	// ExprStmt is an expression used as statement.
+	ExprStmt struct {
+		X Expr
+	}
+
+	// BlockStmt is a list of statements in braces.
+	BlockStmt struct {
+		Lbrace Pos
+		Stmts  []Stmt
+	}
+
+	// LocalStmt declares local variables. It is also
+	// used for local functions.
+	LocalStmt struct {
+		Local Pos
+		Vars  []*Var
+	}
+
+	// FuncDecl declares a function like function A::b(x) {}.
+	FuncDecl struct {
+		Name []*Ident
+		Func *FuncLit
+	}
+
+	// ClassDecl declares a class like class A::B extends C {}.
+	ClassDecl struct {
+		Name  []*Ident
+		Class *ClassLit
+	}
+
+	// EnumDecl declares an enumeration.
+	EnumDecl struct {
+		Enum    Pos
+		Name    *Ident
+		Members []*Var
+	}
+
+	// ConstDecl declares a constant.
+	ConstDecl struct {
+		Const Pos
+		Name  *Ident
+		Value Expr
+	}
+
+	// IfStmt is an if statement. Else is nil without else branch.
+	IfStmt struct {
+		If   Pos
+		Cond Expr
+		Then Stmt
+		Else Stmt
+	}
+
+	// WhileStmt is a while loop.
+	WhileStmt struct {
+		While Pos
+		Cond  Expr
+		Body  Stmt
+	}
+
+	// DoWhileStmt is a do while loop.
+	DoWhileStmt struct {
+		Do   Pos
+		Body Stmt
+		Cond Expr
+	}
+
+	// ForStmt is a for loop. Init is a LocalStmt, an ExprStmt or nil.
+	ForStmt struct {
+		For  Pos
+		Init Stmt
+		Cond Expr
+		Post []Expr
+		Body Stmt
+	}
+
+	// ForeachStmt is a foreach loop. Key is nil if only
+	// the values are iterated.
+	ForeachStmt struct {
+		Foreach Pos
+		Key     *Ident
+		Value   *Ident
+		X       Expr
+		Body    Stmt
+	}
+
+	// SwitchStmt is a switch statement.
+	SwitchStmt struct {
+		Switch Pos
+		Tag    Expr
+		Cases  []*CaseClause
+	}
+
+	// BranchStmt is a break or continue statement.
+	BranchStmt struct {
+		TokPos Pos
+		Tok    Token
+	}
+
+	// ReturnStmt is a return statement. Result may be nil.
+	ReturnStmt struct {
+		Return Pos
+		Result Expr
+	}
+
+	// YieldStmt is a yield statement. Value may be nil.
+	YieldStmt struct {
+		Yield Pos
+		Value Expr
+	}
+
+	// ThrowStmt is a throw statement.
+	ThrowStmt struct {
+		Throw Pos
+		X     Expr
+	}
+
+	// TryStmt is a try catch statement.
+	TryStmt struct {
+		Try     Pos
+		Body    Stmt
+		Var     *Ident
+		Handler Stmt
+	}
+)
+
+// Var is a declared variable or enumeration member.
+// Value is nil without initialization.
+type Var struct {
+	Name  *Ident
+	Value Expr
+}
+
+// Pos implements Node.
+func (v *Var) Pos() Pos { return v.Name.Pos() }
+
+// CaseClause is a case of a switch statement.
+// Value is nil for the default case.
+type CaseClause struct {
+	Case  Pos
+	Value Expr
+	Body  []Stmt
+}
+
+// Pos implements Node.
+func (c *CaseClause) Pos() Pos { return c.Case }
+
+func (x *BadExpr) Pos() Pos      { return x.From }
+func (x *Ident) Pos() Pos        { return x.NamePos }
+func (x *BasicLit) Pos() Pos     { return x.ValuePos }
+func (x *RootExpr) Pos() Pos     { return x.DColon }
+func (x *ParenExpr) Pos() Pos    { return x.Lparen }
+func (x *SelectorExpr) Pos() Pos { return x.X.Pos() }
+func (x *IndexExpr) Pos() Pos    { return x.X.Pos() }
+func (x *CallExpr) Pos() Pos     { return x.Fun.Pos() }
+func (x *UnaryExpr) Pos() Pos    { return x.OpPos }
+func (x *PostfixExpr) Pos() Pos  { return x.X.Pos() }
+func (x *BinaryExpr) Pos() Pos   { return x.X.Pos() }
+func (x *AssignExpr) Pos() Pos   { return x.Lhs.Pos() }
+func (x *CondExpr) Pos() Pos     { return x.Cond.Pos() }
+func (x *ArrayLit) Pos() Pos     { return x.Lbrack }
+func (x *TableLit) Pos() Pos     { return x.Lbrace }
+func (x *FuncLit) Pos() Pos      { return x.Func }
+func (x *ClassLit) Pos() Pos     { return x.Class }
+
+func (*BadExpr) exprNode()      {}
+func (*Ident) exprNode()        {}
+func (*BasicLit) exprNode()     {}
+func (*RootExpr) exprNode()     {}
+func (*ParenExpr) exprNode()    {}
+func (*SelectorExpr) exprNode() {}
+func (*IndexExpr) exprNode()    {}
+func (*CallExpr) exprNode()     {}
+func (*UnaryExpr) exprNode()    {}
+func (*PostfixExpr) exprNode()  {}
+func (*BinaryExpr) exprNode()   {}
+func (*AssignExpr) exprNode()   {}
+func (*CondExpr) exprNode()     {}
+func (*ArrayLit) exprNode()     {}
+func (*TableLit) exprNode()     {}
+func (*FuncLit) exprNode()      {}
+func (*ClassLit) exprNode()     {}
+
+func (s *BadStmt) Pos() Pos     { return s.From }
+func (s *EmptyStmt) Pos() Pos   { return s.Semicolon }
+func (s *ExprStmt) Pos() Pos    { return s.X.Pos() }
+func (s *BlockStmt) Pos() Pos   { return s.Lbrace }
+func (s *LocalStmt) Pos() Pos   { return s.Local }
+func (s *FuncDecl) Pos() Pos    { return s.Func.Func }
+func (s *ClassDecl) Pos() Pos   { return s.Class.Class }
+func (s *EnumDecl) Pos() Pos    { return s.Enum }
+func (s *ConstDecl) Pos() Pos   { return s.Const }
+func (s *IfStmt) Pos() Pos      { return s.If }
+func (s *WhileStmt) Pos() Pos   { return s.While }
+func (s *DoWhileStmt) Pos() Pos { return s.Do }
+func (s *ForStmt) Pos() Pos     { return s.For }
+func (s *ForeachStmt) Pos() Pos { return s.Foreach }
+func (s *SwitchStmt) Pos() Pos  { return s.Switch }
+func (s *BranchStmt) Pos() Pos  { return s.TokPos }
+func (s *ReturnStmt) Pos() Pos  { return s.Return }
+func (s *YieldStmt) Pos() Pos   { return s.Yield }
+func (s *ThrowStmt) Pos() Pos   { return s.Throw }
+func (s *TryStmt) Pos() Pos     { return s.Try }
+
+func (*BadStmt) stmtNode()     {}
+func (*EmptyStmt) stmtNode()   {}
+func (*ExprStmt) stmtNode()    {}
+func (*BlockStmt) stmtNode()   {}
+func (*LocalStmt) stmtNode()   {}
+func (*FuncDecl) stmtNode()    {}
+func (*ClassDecl) stmtNode()   {}
+func (*EnumDecl) stmtNode()    {}
+func (*ConstDecl) stmtNode()   {}
+func (*IfStmt) stmtNode()      {}
+func (*WhileStmt) stmtNode()   {}
+func (*DoWhileStmt) stmtNode() {}
+func (*ForStmt) stmtNode()     {}
+func (*ForeachStmt) stmtNode() {}
+func (*SwitchStmt) stmtNode()  {}
+func (*BranchStmt) stmtNode()  {}
+func (*ReturnStmt) stmtNode()  {}
+func (*YieldStmt) stmtNode()   {}
+func (*ThrowStmt) stmtNode()   {}
+func (*TryStmt) stmtNode()     {}
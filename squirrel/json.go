@@ -0,0 +1,101 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	posType   = reflect.TypeOf(Pos{})
+	tokenType = reflect.TypeOf(Token(0))
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+)
+
+// MarshalJSON encodes a syntax tree as JSON. Every node becomes
+// an object with its Go type name as "type", its position as
+// "pos" and its non-empty fields in the order of declaration.
+// Tokens are written as strings.
+func MarshalJSON(n Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	w := jsonWriter{buf: &buf, enc: enc}
+	if err := w.write(reflect.ValueOf(n)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type jsonWriter struct {
+	buf *bytes.Buffer
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) scalar(x interface{}) error {
+	if err := w.enc.Encode(x); err != nil {
+		return err
+	}
+	// Remove the newline added by the encoder.
+	w.buf.Truncate(w.buf.Len() - 1)
+	return nil
+}
+
+func (w *jsonWriter) write(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			w.buf.WriteString("null")
+			return nil
+		}
+		return w.write(v.Elem())
+
+	case reflect.Slice:
+		w.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.write(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+		return nil
+
+	case reflect.Struct:
+		t := v.Type()
+		w.buf.WriteString(`{"type":`)
+		w.scalar(t.Name())
+		if v.CanAddr() && v.Addr().Type().Implements(nodeType) {
+			w.buf.WriteString(`,"pos":`)
+			w.scalar(v.Addr().Interface().(Node).Pos().String())
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f, fv := t.Field(i), v.Field(i)
+			if f.Type == posType || fv.IsZero() {
+				continue
+			}
+			w.buf.WriteByte(',')
+			w.scalar(f.Name)
+			w.buf.WriteByte(':')
+			if err := w.write(fv); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte('}')
+		return nil
+	}
+
+	if v.Type() == tokenType {
+		return w.scalar(v.Interface().(Token).String())
+	}
+	return w.scalar(v.Interface())
+}
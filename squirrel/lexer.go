@@ -0,0 +1,384 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a syntax error.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorList is a list of syntax errors in the order they were found.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+	}
+}
+
+// Err returns the list as error or nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Lexeme is a token found by the Lexer.
+type Lexeme struct {
+	Tok Token
+	Pos Pos
+	// Lit is the source text of identifiers, keywords and numbers,
+	// the decoded content of strings and characters and the text
+	// of operators.
+	Lit string
+	// Newline reports if the token is the first one on its line.
+	// Squirrel uses newlines to terminate statements.
+	Newline bool
+}
+
+// Lexer splits Squirrel source into tokens. Comments are skipped.
+// Errors are collected and scanning continues behind them.
+type Lexer struct {
+	src     []byte
+	offset  int
+	line    int
+	col     int
+	started bool
+	// Errors are the errors found so far.
+	Errors ErrorList
+}
+
+// NewLexer creates a Lexer over the given source.
+func NewLexer(src []byte) *Lexer {
+	return &Lexer{src: src, line: 1, col: 1}
+}
+
+// Tokenize returns all tokens of the source up to and including EOF.
+func Tokenize(src []byte) ([]Lexeme, error) {
+	l := NewLexer(src)
+	var lexemes []Lexeme
+	for {
+		lx := l.Next()
+		lexemes = append(lexemes, lx)
+		if lx.Tok == EOF {
+			return lexemes, l.Errors.Err()
+		}
+	}
+}
+
+func (l *Lexer) pos() Pos {
+	return Pos{Offset: l.offset, Line: l.line, Column: l.col}
+}
+
+func (l *Lexer) errorf(pos Pos, format string, args ...interface{}) {
+	l.Errors = append(l.Errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// peek returns the byte n bytes ahead or 0 at the end.
+func (l *Lexer) peek(n int) byte {
+	if l.offset+n < len(l.src) {
+		return l.src[l.offset+n]
+	}
+	return 0
+}
+
+func (l *Lexer) advance() {
+	if l.offset >= len(l.src) {
+		return
+	}
+	if l.src[l.offset] == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	l.offset++
+}
+
+// skip skips white space and comments and reports
+// if a newline was found.
+func (l *Lexer) skip() bool {
+	nl := false
+	for l.offset < len(l.src) {
+		switch c := l.src[l.offset]; {
+		case c == '\n':
+			nl = true
+			l.advance()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.advance()
+		case c == '#' || (c == '/' && l.peek(1) == '/'):
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.advance()
+			}
+		case c == '/' && l.peek(1) == '*':
+			start := l.pos()
+			l.advance()
+			l.advance()
+			for {
+				if l.offset >= len(l.src) {
+					l.errorf(start, "comment not terminated")
+					return nl
+				}
+				if l.src[l.offset] == '*' && l.peek(1) == '/' {
+					l.advance()
+					l.advance()
+					break
+				}
+				if l.src[l.offset] == '\n' {
+					nl = true
+				}
+				l.advance()
+			}
+		default:
+			return nl
+		}
+	}
+	return nl
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// operators maps the operators to their tokens. Longer operators
+// are matched first.
+var operators = func() map[string]Token {
+	m := make(map[string]Token, operatorEnd-operatorBeg)
+	for tok := operatorBeg + 1; tok < operatorEnd; tok++ {
+		m[tokens[tok]] = tok
+	}
+	return m
+}()
+
+// Next returns the next token. At the end of the source
+// EOF is returned repeatedly.
+func (l *Lexer) Next() Lexeme {
+	nl := l.skip() || !l.started
+	l.started = true
+	pos := l.pos()
+	lx := Lexeme{Pos: pos, Newline: nl}
+
+	if l.offset >= len(l.src) {
+		lx.Tok = EOF
+		return lx
+	}
+
+	c := l.src[l.offset]
+	switch {
+	case isLetter(c):
+		start := l.offset
+		for l.offset < len(l.src) && (isLetter(l.src[l.offset]) || isDigit(l.src[l.offset])) {
+			l.advance()
+		}
+		lx.Lit = string(l.src[start:l.offset])
+		lx.Tok = Lookup(lx.Lit)
+		return lx
+
+	case isDigit(c):
+		lx.Tok, lx.Lit = l.number()
+		return lx
+
+	case c == '"':
+		l.advance()
+		lx.Tok, lx.Lit = STRING, l.quoted(pos, '"')
+		return lx
+
+	case c == '\'':
+		l.advance()
+		lx.Tok, lx.Lit = CHAR, l.quoted(pos, '\'')
+		if utf8.RuneCountInString(lx.Lit) != 1 {
+			l.errorf(pos, "invalid character literal")
+		}
+		return lx
+
+	case c == '@' && l.peek(1) == '"':
+		l.advance()
+		l.advance()
+		lx.Tok, lx.Lit = STRING, l.verbatim(pos)
+		return lx
+	}
+
+	for n := 3; n > 0; n-- {
+		if l.offset+n > len(l.src) {
+			continue
+		}
+		op := string(l.src[l.offset : l.offset+n])
+		if tok, ok := operators[op]; ok {
+			for i := 0; i < n; i++ {
+				l.advance()
+			}
+			lx.Tok, lx.Lit = tok, op
+			return lx
+		}
+	}
+
+	r, size := utf8.DecodeRune(l.src[l.offset:])
+	for i := 0; i < size; i++ {
+		l.advance()
+	}
+	l.errorf(pos, "unexpected character %q", r)
+	lx.Tok, lx.Lit = ILLEGAL, string(r)
+	return lx
+}
+
+func (l *Lexer) number() (Token, string) {
+	start, pos := l.offset, l.pos()
+
+	if l.src[l.offset] == '0' && (l.peek(1) == 'x' || l.peek(1) == 'X') {
+		l.advance()
+		l.advance()
+		for l.offset < len(l.src) && isHex(l.src[l.offset]) {
+			l.advance()
+		}
+		if l.offset-start == 2 {
+			l.errorf(pos, "invalid hexadecimal number")
+		}
+		return INT, string(l.src[start:l.offset])
+	}
+
+	tok := INT
+	for l.offset < len(l.src) && isDigit(l.src[l.offset]) {
+		l.advance()
+	}
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		tok = FLOAT
+		l.advance()
+		for l.offset < len(l.src) && isDigit(l.src[l.offset]) {
+			l.advance()
+		}
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		n := 1
+		if s := l.peek(1); s == '+' || s == '-' {
+			n++
+		}
+		if isDigit(l.peek(n)) {
+			tok = FLOAT
+			for ; n > 0; n-- {
+				l.advance()
+			}
+			for l.offset < len(l.src) && isDigit(l.src[l.offset]) {
+				l.advance()
+			}
+		}
+	}
+	return tok, string(l.src[start:l.offset])
+}
+
+// quoted scans the rest of a string or character literal
+// and returns its decoded content.
+func (l *Lexer) quoted(pos Pos, quote byte) string {
+	var b strings.Builder
+	for {
+		if l.offset >= len(l.src) {
+			l.errorf(pos, "literal not terminated")
+			return b.String()
+		}
+		c := l.src[l.offset]
+		if c == quote {
+			l.advance()
+			return b.String()
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			l.advance()
+			continue
+		}
+		epos := l.pos()
+		l.advance()
+		e := l.peek(0)
+		l.advance()
+		switch e {
+		case 't':
+			b.WriteByte('\t')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case '0':
+			b.WriteByte(0)
+		case '\\', '"', '\'':
+			b.WriteByte(e)
+		case 'x', 'u', 'U':
+			max := 4
+			if e == 'U' {
+				max = 8
+			}
+			start := l.offset
+			for l.offset-start < max && isHex(l.peek(0)) {
+				l.advance()
+			}
+			if l.offset == start {
+				l.errorf(epos, "invalid escape sequence")
+				continue
+			}
+			v, _ := strconv.ParseUint(string(l.src[start:l.offset]), 16, 32)
+			if e == 'x' && v < 0x100 {
+				b.WriteByte(byte(v))
+			} else {
+				b.WriteRune(rune(v))
+			}
+		default:
+			l.errorf(epos, "invalid escape sequence")
+			b.WriteByte(e)
+		}
+	}
+}
+
+// verbatim scans the rest of a verbatim string @"...".
+// Doubled quotes stand for a single quote.
+func (l *Lexer) verbatim(pos Pos) string {
+	var b strings.Builder
+	for {
+		if l.offset >= len(l.src) {
+			l.errorf(pos, "string not terminated")
+			return b.String()
+		}
+		c := l.src[l.offset]
+		l.advance()
+		if c == '"' {
+			if l.peek(0) != '"' {
+				return b.String()
+			}
+			l.advance()
+		}
+		b.WriteByte(c)
+	}
+}
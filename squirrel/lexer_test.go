@@ -0,0 +1,93 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lexemes formats the tokens of src except EOF as
+// "tok lit" with a leading "\n" for tokens on a new line.
+func lexemes(src string) (string, []string) {
+	l := NewLexer([]byte(src))
+	var parts []string
+	for lx := l.Next(); lx.Tok != EOF; lx = l.Next() {
+		s := lx.Tok.String()
+		if lx.Tok.IsLiteral() {
+			s = fmt.Sprintf("%s %q", s, lx.Lit)
+		}
+		if lx.Newline && len(parts) > 0 {
+			s = "\n" + s
+		}
+		parts = append(parts, s)
+	}
+	var errs []string
+	for _, err := range l.Errors {
+		errs = append(errs, err.Error())
+	}
+	return strings.Join(parts, " "), errs
+}
+
+func TestLexer(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		tokens string
+		errs   []string
+	}{
+		{src: "", tokens: ""},
+		{src: "x <- 1", tokens: `IDENT "x" <- INT "1"`},
+		{src: "a <= b < c", tokens: `IDENT "a" <= IDENT "b" < IDENT "c"`},
+		{src: "a >>> 2 >> 1", tokens: `IDENT "a" >>> INT "2" >> INT "1"`},
+		{src: "local x\ny", tokens: "local IDENT \"x\" \nIDENT \"y\""},
+		{src: "1.5 2e3 0x1F 7", tokens: `FLOAT "1.5" FLOAT "2e3" INT "0x1F" INT "7"`},
+		{src: "@(x) x", tokens: `@ ( IDENT "x" ) IDENT "x"`},
+		{src: `"a\tb\x41"`, tokens: `STRING "a\tbA"`},
+		{src: `@"a""b\n"`, tokens: `STRING "a\"b\\n"`},
+		{src: "@\"a\nb\"", tokens: `STRING "a\nb"`},
+		{src: "'c'", tokens: `CHAR "c"`},
+		{src: "a // c\n# c\nb", tokens: "IDENT \"a\" \nIDENT \"b\""},
+		{src: "a /* x\n */ b", tokens: "IDENT \"a\" \nIDENT \"b\""},
+		{src: "a /* x */ b", tokens: `IDENT "a" IDENT "b"`},
+		{src: `"// no comment"`, tokens: `STRING "// no comment"`},
+		{src: "</ x />", tokens: `</ IDENT "x" />`},
+		{
+			src:    `"abc`,
+			tokens: `STRING "abc"`,
+			errs:   []string{"1:1: literal not terminated"},
+		},
+		{
+			src:    "a /* b",
+			tokens: `IDENT "a"`,
+			errs:   []string{"1:3: comment not terminated"},
+		},
+		{
+			src:    "a $ b",
+			tokens: `IDENT "a" ILLEGAL IDENT "b"`,
+			errs:   []string{`1:3: unexpected character '$'`},
+		},
+		{
+			src:    `"\q" 'ab' 0x`,
+			tokens: `STRING "q" CHAR "ab" INT "0x"`,
+			errs: []string{
+				"1:2: invalid escape sequence",
+				"1:6: invalid character literal",
+				"1:11: invalid hexadecimal number",
+			},
+		},
+	} {
+		tokens, errs := lexemes(tc.src)
+		if tokens != tc.tokens {
+			t.Errorf("%q: got tokens\n%s\nwant\n%s", tc.src, tokens, tc.tokens)
+		}
+		if strings.Join(errs, "|") != strings.Join(tc.errs, "|") {
+			t.Errorf("%q: got errors %q, want %q", tc.src, errs, tc.errs)
+		}
+	}
+}
@@ -0,0 +1,831 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+import "fmt"
+
+// maxErrors is the number of errors after which parsing stops.
+const maxErrors = 100
+
+// bailout is used to unwind the parser to the next
+// point of recovery after a syntax error.
+type bailout struct{}
+
+// tooManyErrors aborts parsing.
+type tooManyErrors struct{}
+
+type parser struct {
+	lex    *Lexer
+	tok    Lexeme
+	errors ErrorList
+	// primary is an already parsed primary expression
+	// the next expression starts with.
+	primary Expr
+}
+
+// Parse parses Squirrel source into a syntax tree. The parser is
+// tolerant: Statements and slots of tables and classes which cannot
+// be parsed are skipped up to the end of their line and show up as
+// BadStmt and BadExpr. The syntax errors are returned as ErrorList
+// along with the tree.
+func Parse(src []byte) (script *Script, err error) {
+	p := &parser{lex: NewLexer(src)}
+	script = &Script{}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(tooManyErrors); !ok {
+				panic(r)
+			}
+		}
+		errs := append(p.lex.Errors, p.errors...)
+		sortErrors(errs)
+		err = errs.Err()
+	}()
+
+	p.next()
+	script.Stmts = p.parseStmtList(EOF)
+	if p.tok.Tok != EOF {
+		p.errorExpected("EOF")
+	}
+	return script, nil
+}
+
+func sortErrors(errs ErrorList) {
+	// Insertion sort as the lists are mostly sorted.
+	for i := 1; i < len(errs); i++ {
+		for j := i; j > 0 && errs[j].Pos.Offset < errs[j-1].Pos.Offset; j-- {
+			errs[j], errs[j-1] = errs[j-1], errs[j]
+		}
+	}
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.Next()
+}
+
+func (p *parser) error(pos Pos, msg string) {
+	p.errors = append(p.errors, &Error{Pos: pos, Msg: msg})
+	if len(p.errors)+len(p.lex.Errors) > maxErrors {
+		panic(tooManyErrors{})
+	}
+}
+
+func (p *parser) describe() string {
+	switch {
+	case p.tok.Tok == EOF:
+		return "EOF"
+	case p.tok.Tok == STRING:
+		return fmt.Sprintf("string %q", p.tok.Lit)
+	case p.tok.Tok.IsLiteral():
+		return fmt.Sprintf("%s %s", p.tok.Tok, p.tok.Lit)
+	default:
+		return fmt.Sprintf("'%s'", p.tok.Tok)
+	}
+}
+
+// errorExpected reports a syntax error and bails out.
+func (p *parser) errorExpected(what string) {
+	p.error(p.tok.Pos, fmt.Sprintf("expected %s, found %s", what, p.describe()))
+	panic(bailout{})
+}
+
+func (p *parser) expect(tok Token) Pos {
+	pos := p.tok.Pos
+	if p.tok.Tok != tok {
+		p.errorExpected("'" + tok.String() + "'")
+	}
+	p.next()
+	return pos
+}
+
+func (p *parser) got(tok Token) bool {
+	if p.tok.Tok == tok {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func isCloser(tok Token) bool {
+	switch tok {
+	case RBRACE, RBRACK, RPAREN, ATTR_CLOSE, EOF:
+		return true
+	}
+	return false
+}
+
+// sync skips tokens after a syntax error up to the end of the
+// line, a separator or a closing bracket on the current level.
+func (p *parser) sync(start Pos) {
+	depth := 0
+	for p.tok.Tok != EOF {
+		tok := p.tok.Tok
+		if depth == 0 {
+			if tok == SEMICOLON || tok == COMMA {
+				p.next()
+				return
+			}
+			if isCloser(tok) || (p.tok.Newline && p.tok.Pos.Offset > start.Offset) {
+				return
+			}
+		}
+		switch {
+		case tok == LBRACE || tok == LBRACK || tok == LPAREN || tok == ATTR_OPEN:
+			depth++
+		case isCloser(tok) && depth > 0:
+			depth--
+		}
+		p.next()
+	}
+}
+
+// recover runs fn and recovers from a syntax error by
+// skipping the rest of the construct. It reports if fn
+// succeeded.
+func (p *parser) recover(fn func()) bool {
+	start := p.tok.Pos
+	return p.recoverWith(fn, func() {
+		p.sync(start)
+		// Make sure to make progress.
+		if p.tok.Pos.Offset == start.Offset && p.tok.Tok != EOF {
+			p.next()
+		}
+	})
+}
+
+// recoverWith runs fn and calls skip to recover from a syntax
+// error. It reports if fn succeeded.
+func (p *parser) recoverWith(fn, skip func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isBailout := r.(bailout); !isBailout {
+				panic(r)
+			}
+			skip()
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// Statements
+
+func (p *parser) parseStmtList(ends ...Token) []Stmt {
+	var list []Stmt
+	for {
+		for _, end := range ends {
+			if p.tok.Tok == end {
+				return list
+			}
+		}
+		if p.tok.Tok == EOF {
+			return list
+		}
+		var stmt Stmt
+		start := p.tok.Pos
+		if !p.recover(func() { stmt = p.parseStmt() }) {
+			stmt = &BadStmt{From: start}
+		}
+		list = append(list, stmt)
+	}
+}
+
+// endStmt checks that a statement is terminated by a semicolon,
+// a newline, a closing brace or the end of the source.
+func (p *parser) endStmt() {
+	switch {
+	case p.tok.Tok == SEMICOLON:
+		p.next()
+	case p.atStmtEnd(), p.tok.Tok == ELSE, p.tok.Tok == CASE, p.tok.Tok == DEFAULT:
+	default:
+		p.errorExpected("';' or newline")
+	}
+}
+
+func (p *parser) atStmtEnd() bool {
+	switch p.tok.Tok {
+	case SEMICOLON, RBRACE, EOF:
+		return true
+	}
+	return p.tok.Newline
+}
+
+func (p *parser) parseStmt() Stmt {
+	switch p.tok.Tok {
+	case SEMICOLON:
+		s := &EmptyStmt{Semicolon: p.tok.Pos}
+		p.next()
+		return s
+
+	case LBRACE:
+		return p.parseBlock()
+
+	case LOCAL:
+		return p.parseLocal(true)
+
+	case FUNCTION:
+		pos := p.tok.Pos
+		p.next()
+		if p.tok.Tok == IDENT {
+			name := p.parseQualifiedName()
+			return &FuncDecl{Name: name, Func: p.parseFuncRest(pos)}
+		}
+		p.primary = p.parseFuncRest(pos)
+
+	case CLASS:
+		pos := p.tok.Pos
+		p.next()
+		if p.tok.Tok == IDENT {
+			name := p.parseQualifiedName()
+			return &ClassDecl{Name: name, Class: p.parseClassRest(pos)}
+		}
+		p.primary = p.parseClassRest(pos)
+
+	case ENUM:
+		return p.parseEnum()
+
+	case CONST:
+		s := &ConstDecl{Const: p.tok.Pos}
+		p.next()
+		s.Name = p.parseIdent()
+		p.expect(ASSIGN)
+		s.Value = p.parseExpr()
+		p.endStmt()
+		return s
+
+	case IF:
+		s := &IfStmt{If: p.tok.Pos}
+		p.next()
+		s.Cond = p.parseParenExpr()
+		s.Then = p.parseStmt()
+		if p.got(ELSE) {
+			s.Else = p.parseStmt()
+		}
+		return s
+
+	case WHILE:
+		s := &WhileStmt{While: p.tok.Pos}
+		p.next()
+		s.Cond = p.parseParenExpr()
+		s.Body = p.parseStmt()
+		return s
+
+	case DO:
+		s := &DoWhileStmt{Do: p.tok.Pos}
+		p.next()
+		s.Body = p.parseStmt()
+		p.expect(WHILE)
+		s.Cond = p.parseParenExpr()
+		return s
+
+	case FOR:
+		return p.parseFor()
+
+	case FOREACH:
+		return p.parseForeach()
+
+	case SWITCH:
+		return p.parseSwitch()
+
+	case BREAK, CONTINUE:
+		s := &BranchStmt{TokPos: p.tok.Pos, Tok: p.tok.Tok}
+		p.next()
+		p.endStmt()
+		return s
+
+	case RETURN:
+		s := &ReturnStmt{Return: p.tok.Pos}
+		p.next()
+		if !p.atStmtEnd() {
+			s.Result = p.parseExpr()
+		}
+		p.endStmt()
+		return s
+
+	case YIELD:
+		s := &YieldStmt{Yield: p.tok.Pos}
+		p.next()
+		if !p.atStmtEnd() {
+			s.Value = p.parseExpr()
+		}
+		p.endStmt()
+		return s
+
+	case THROW:
+		s := &ThrowStmt{Throw: p.tok.Pos}
+		p.next()
+		s.X = p.parseExpr()
+		p.endStmt()
+		return s
+
+	case TRY:
+		s := &TryStmt{Try: p.tok.Pos}
+		p.next()
+		s.Body = p.parseStmt()
+		p.expect(CATCH)
+		p.expect(LPAREN)
+		s.Var = p.parseIdent()
+		p.expect(RPAREN)
+		s.Handler = p.parseStmt()
+		return s
+	}
+
+	s := &ExprStmt{X: p.parseExpr()}
+	p.endStmt()
+	return s
+}
+
+func (p *parser) parseBlock() *BlockStmt {
+	b := &BlockStmt{Lbrace: p.expect(LBRACE)}
+	b.Stmts = p.parseStmtList(RBRACE)
+	p.expect(RBRACE)
+	return b
+}
+
+func (p *parser) parseLocal(terminated bool) *LocalStmt {
+	s := &LocalStmt{Local: p.expect(LOCAL)}
+	if p.tok.Tok == FUNCTION {
+		pos := p.tok.Pos
+		p.next()
+		name := p.parseIdent()
+		s.Vars = []*Var{{Name: name, Value: p.parseFuncRest(pos)}}
+		return s
+	}
+	for {
+		v := &Var{Name: p.parseIdent()}
+		if p.got(ASSIGN) {
+			v.Value = p.parseExpr()
+		}
+		s.Vars = append(s.Vars, v)
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	if terminated {
+		p.endStmt()
+	}
+	return s
+}
+
+func (p *parser) parseEnum() *EnumDecl {
+	s := &EnumDecl{Enum: p.expect(ENUM)}
+	s.Name = p.parseIdent()
+	p.expect(LBRACE)
+	for p.tok.Tok != RBRACE && p.tok.Tok != EOF {
+		v := &Var{Name: p.parseIdent()}
+		if p.got(ASSIGN) {
+			v.Value = p.parseExpr()
+		}
+		s.Members = append(s.Members, v)
+		p.got(COMMA)
+	}
+	p.expect(RBRACE)
+	return s
+}
+
+func (p *parser) parseFor() *ForStmt {
+	s := &ForStmt{For: p.expect(FOR)}
+	p.expect(LPAREN)
+	switch p.tok.Tok {
+	case SEMICOLON:
+	case LOCAL:
+		s.Init = p.parseLocal(false)
+	default:
+		s.Init = &ExprStmt{X: p.parseExpr()}
+	}
+	p.expect(SEMICOLON)
+	if p.tok.Tok != SEMICOLON {
+		s.Cond = p.parseExpr()
+	}
+	p.expect(SEMICOLON)
+	for p.tok.Tok != RPAREN {
+		s.Post = append(s.Post, p.parseExpr())
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	p.expect(RPAREN)
+	s.Body = p.parseStmt()
+	return s
+}
+
+func (p *parser) parseForeach() *ForeachStmt {
+	s := &ForeachStmt{Foreach: p.expect(FOREACH)}
+	p.expect(LPAREN)
+	s.Value = p.parseIdent()
+	if p.got(COMMA) {
+		s.Key, s.Value = s.Value, p.parseIdent()
+	}
+	p.expect(IN)
+	s.X = p.parseExpr()
+	p.expect(RPAREN)
+	s.Body = p.parseStmt()
+	return s
+}
+
+func (p *parser) parseSwitch() *SwitchStmt {
+	s := &SwitchStmt{Switch: p.expect(SWITCH)}
+	s.Tag = p.parseParenExpr()
+	p.expect(LBRACE)
+	for p.tok.Tok == CASE || p.tok.Tok == DEFAULT {
+		c := &CaseClause{Case: p.tok.Pos}
+		if p.tok.Tok == CASE {
+			p.next()
+			c.Value = p.parseExpr()
+		} else {
+			p.next()
+		}
+		p.expect(COLON)
+		c.Body = p.parseStmtList(CASE, DEFAULT, RBRACE)
+		s.Cases = append(s.Cases, c)
+	}
+	p.expect(RBRACE)
+	return s
+}
+
+// Names
+
+func (p *parser) parseIdent() *Ident {
+	id := &Ident{NamePos: p.tok.Pos, Name: p.tok.Lit}
+	if p.tok.Tok != IDENT {
+		p.errorExpected("identifier")
+	}
+	p.next()
+	return id
+}
+
+// parseName parses an identifier. Keywords are accepted as
+// names as they are used as slot names in the game scripts.
+func (p *parser) parseName() *Ident {
+	if p.tok.Tok.IsKeyword() {
+		id := &Ident{NamePos: p.tok.Pos, Name: p.tok.Lit}
+		p.next()
+		return id
+	}
+	return p.parseIdent()
+}
+
+// parseQualifiedName parses names like A::b or A.b.
+func (p *parser) parseQualifiedName() []*Ident {
+	name := []*Ident{p.parseIdent()}
+	for p.tok.Tok == DCOLON || p.tok.Tok == PERIOD {
+		p.next()
+		name = append(name, p.parseName())
+	}
+	return name
+}
+
+// Functions and classes
+
+// parseParamList parses a parameter list in parentheses. A broken
+// list is skipped up to its closing parenthesis or the opening brace
+// of the body so that the body is still parsed.
+func (p *parser) parseParamList() (params []*Param, varargs bool) {
+	p.expect(LPAREN)
+	p.recoverWith(func() {
+		params, varargs = p.parseParams(RPAREN)
+	}, p.skipParams)
+	return params, varargs
+}
+
+// skipParams skips the rest of a broken parameter list.
+func (p *parser) skipParams() {
+	depth := 0
+	for p.tok.Tok != EOF {
+		switch p.tok.Tok {
+		case LPAREN, LBRACK:
+			depth++
+		case RPAREN, RBRACK:
+			if depth == 0 {
+				p.got(RPAREN)
+				return
+			}
+			depth--
+		case LBRACE:
+			if depth == 0 {
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseParams(end Token) ([]*Param, bool) {
+	var params []*Param
+	varargs := false
+	for p.tok.Tok != end && p.tok.Tok != EOF {
+		if p.got(ELLIPSIS) {
+			varargs = true
+			break
+		}
+		param := &Param{Name: p.parseIdent()}
+		if p.got(ASSIGN) {
+			param.Default = p.parseExpr()
+		}
+		params = append(params, param)
+		if !p.got(COMMA) {
+			break
+		}
+	}
+	p.expect(end)
+	return params, varargs
+}
+
+func (p *parser) parseFuncRest(pos Pos) *FuncLit {
+	fn := &FuncLit{Func: pos}
+	fn.Params, fn.Varargs = p.parseParamList()
+	fn.Body = p.parseStmt()
+	return fn
+}
+
+func (p *parser) parseClassRest(pos Pos) *ClassLit {
+	c := &ClassLit{Class: pos}
+	if p.got(EXTENDS) {
+		c.Extends = p.parsePostfix()
+	}
+	if p.tok.Tok == ATTR_OPEN {
+		c.Attrs = p.parseAttrs()
+	}
+	p.expect(LBRACE)
+	c.Members = p.parseSlots(RBRACE, true)
+	p.expect(RBRACE)
+	return c
+}
+
+func (p *parser) parseAttrs() *TableLit {
+	t := &TableLit{Lbrace: p.expect(ATTR_OPEN)}
+	t.Slots = p.parseSlots(ATTR_CLOSE, false)
+	p.expect(ATTR_CLOSE)
+	return t
+}
+
+// parseSlots parses the slots of a table or the members of a class.
+func (p *parser) parseSlots(end Token, class bool) []*Slot {
+	var slots []*Slot
+	for p.tok.Tok != end && p.tok.Tok != EOF {
+		start := p.tok.Pos
+		var slot *Slot
+		if !p.recover(func() { slot = p.parseSlot(class) }) {
+			slot = &Slot{Key: &BadExpr{From: start}, Value: &BadExpr{From: start}}
+		}
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+func (p *parser) parseSlot(class bool) *Slot {
+	slot := &Slot{}
+	if p.tok.Tok == ATTR_OPEN {
+		slot.Attrs = p.parseAttrs()
+	}
+	if class && p.got(STATIC) {
+		slot.Static = true
+	}
+
+	switch p.tok.Tok {
+	case FUNCTION:
+		pos := p.tok.Pos
+		p.next()
+		slot.Key = p.parseName()
+		slot.Value = p.parseFuncRest(pos)
+
+	case CONSTRUCTOR:
+		pos := p.tok.Pos
+		slot.Key = p.parseName()
+		slot.Value = p.parseFuncRest(pos)
+
+	case LBRACK:
+		p.next()
+		slot.Key, slot.Computed = p.parseExpr(), true
+		p.expect(RBRACK)
+		p.expect(ASSIGN)
+		slot.Value = p.parseExpr()
+
+	case STRING:
+		slot.Key = &BasicLit{ValuePos: p.tok.Pos, Kind: STRING, Value: p.tok.Lit}
+		p.next()
+		if !p.got(COLON) {
+			p.expect(ASSIGN)
+		}
+		slot.Value = p.parseExpr()
+
+	default:
+		slot.Key = p.parseName()
+		p.expect(ASSIGN)
+		slot.Value = p.parseExpr()
+	}
+
+	if !p.got(COMMA) {
+		p.got(SEMICOLON)
+	}
+	return slot
+}
+
+// Expressions
+
+func (p *parser) parseParenExpr() Expr {
+	p.expect(LPAREN)
+	x := p.parseExpr()
+	p.expect(RPAREN)
+	return x
+}
+
+func isAssign(tok Token) bool {
+	switch tok {
+	case ASSIGN, NEWSLOT, ADD_ASSIGN, SUB_ASSIGN, MUL_ASSIGN, QUO_ASSIGN, REM_ASSIGN:
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseExpr() Expr {
+	x := p.parseCond()
+	if isAssign(p.tok.Tok) {
+		op := p.tok.Tok
+		p.next()
+		return &AssignExpr{Lhs: x, Op: op, Rhs: p.parseExpr()}
+	}
+	return x
+}
+
+func (p *parser) parseCond() Expr {
+	x := p.parseBinary(1)
+	if !p.got(QUESTION) {
+		return x
+	}
+	c := &CondExpr{Cond: x}
+	c.Then = p.parseExpr()
+	p.expect(COLON)
+	c.Else = p.parseExpr()
+	return c
+}
+
+func precedence(tok Token) int {
+	switch tok {
+	case LOR:
+		return 1
+	case LAND, IN, INSTANCEOF:
+		return 2
+	case OR:
+		return 3
+	case XOR:
+		return 4
+	case AND:
+		return 5
+	case EQL, NEQ, CMP:
+		return 6
+	case LSS, GTR, LEQ, GEQ:
+		return 7
+	case SHL, SHR, USHR:
+		return 8
+	case ADD, SUB:
+		return 9
+	case MUL, QUO, REM:
+		return 10
+	}
+	return 0
+}
+
+func (p *parser) parseBinary(prec int) Expr {
+	x := p.parseUnary()
+	for {
+		op := p.tok.Tok
+		opPrec := precedence(op)
+		if opPrec < prec || opPrec == 0 {
+			return x
+		}
+		p.next()
+		x = &BinaryExpr{X: x, Op: op, Y: p.parseBinary(opPrec + 1)}
+	}
+}
+
+func (p *parser) parseUnary() Expr {
+	switch p.tok.Tok {
+	case SUB, NOT, BNOT, TYPEOF, CLONE, DELETE, RESUME, INC, DEC:
+		if p.primary != nil {
+			break
+		}
+		x := &UnaryExpr{OpPos: p.tok.Pos, Op: p.tok.Tok}
+		p.next()
+		x.X = p.parseUnary()
+		return x
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() Expr {
+	x := p.parsePrimary()
+	for {
+		switch p.tok.Tok {
+		case PERIOD:
+			p.next()
+			x = &SelectorExpr{X: x, Sel: p.parseName()}
+
+		case LBRACK:
+			// A bracket on a new line starts a new element
+			// of an array or a new slot of a table.
+			if p.tok.Newline {
+				return x
+			}
+			p.next()
+			idx := &IndexExpr{X: x, Index: p.parseExpr()}
+			p.expect(RBRACK)
+			x = idx
+
+		case LPAREN:
+			p.next()
+			call := &CallExpr{Fun: x}
+			for p.tok.Tok != RPAREN && p.tok.Tok != EOF {
+				call.Args = append(call.Args, p.parseExpr())
+				if !p.got(COMMA) {
+					break
+				}
+			}
+			p.expect(RPAREN)
+			x = call
+
+		case INC, DEC:
+			if p.tok.Newline {
+				return x
+			}
+			x = &PostfixExpr{X: x, Op: p.tok.Tok}
+			p.next()
+
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) parsePrimary() Expr {
+
+	if x := p.primary; x != nil {
+		p.primary = nil
+		return x
+	}
+
+	pos := p.tok.Pos
+
+	switch p.tok.Tok {
+	case IDENT, THIS, BASE, LINE, FILE:
+		x := &Ident{NamePos: pos, Name: p.tok.Lit}
+		p.next()
+		return x
+
+	case INT, FLOAT, STRING, CHAR, NULL, TRUE, FALSE:
+		x := &BasicLit{ValuePos: pos, Kind: p.tok.Tok, Value: p.tok.Lit}
+		p.next()
+		return x
+
+	case DCOLON:
+		p.next()
+		return &RootExpr{DColon: pos, Name: p.parseName()}
+
+	case LPAREN:
+		p.next()
+		x := &ParenExpr{Lparen: pos, X: p.parseExpr()}
+		p.expect(RPAREN)
+		return x
+
+	case LBRACK:
+		p.next()
+		a := &ArrayLit{Lbrack: pos}
+		for p.tok.Tok != RBRACK && p.tok.Tok != EOF {
+			a.Elems = append(a.Elems, p.parseExpr())
+			p.got(COMMA)
+		}
+		p.expect(RBRACK)
+		return a
+
+	case LBRACE:
+		p.next()
+		t := &TableLit{Lbrace: pos, Slots: p.parseSlots(RBRACE, false)}
+		p.expect(RBRACE)
+		return t
+
+	case FUNCTION:
+		p.next()
+		return p.parseFuncRest(pos)
+
+	case AT:
+		p.next()
+		fn := &FuncLit{Func: pos, Lambda: true}
+		fn.Params, fn.Varargs = p.parseParamList()
+		fn.Body = &ExprStmt{X: p.parseExpr()}
+		return fn
+
+	case CLASS:
+		p.next()
+		return p.parseClassRest(pos)
+	}
+
+	p.errorExpected("expression")
+	return nil
+}
@@ -0,0 +1,196 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parseErrors(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var errs []string
+	for _, e := range err.(ErrorList) {
+		errs = append(errs, e.Error())
+	}
+	return errs
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		json string
+	}{
+		{
+			src: "x <- 1",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"ExprStmt","pos":"1:1","X":` +
+				`{"type":"AssignExpr","pos":"1:1","Lhs":{"type":"Ident","pos":"1:1","Name":"x"},` +
+				`"Op":"<-","Rhs":{"type":"BasicLit","pos":"1:6","Kind":"INT","Value":"1"}}}]}`,
+		},
+		{
+			src: "return\nx",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"ReturnStmt","pos":"1:1"},` +
+				`{"type":"ExprStmt","pos":"2:1","X":{"type":"Ident","pos":"2:1","Name":"x"}}]}`,
+		},
+		{
+			src: "f(1)\n[2]",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"ExprStmt","pos":"1:1","X":` +
+				`{"type":"CallExpr","pos":"1:1","Fun":{"type":"Ident","pos":"1:1","Name":"f"},` +
+				`"Args":[{"type":"BasicLit","pos":"1:3","Kind":"INT","Value":"1"}]}},` +
+				`{"type":"ExprStmt","pos":"2:1","X":{"type":"ArrayLit","pos":"2:1",` +
+				`"Elems":[{"type":"BasicLit","pos":"2:2","Kind":"INT","Value":"2"}]}}]}`,
+		},
+		{
+			src: "local f = @(x) x * 2",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"LocalStmt","pos":"1:1","Vars":` +
+				`[{"type":"Var","pos":"1:7","Name":{"type":"Ident","pos":"1:7","Name":"f"},` +
+				`"Value":{"type":"FuncLit","pos":"1:11","Lambda":true,"Params":[{"type":"Param",` +
+				`"pos":"1:13","Name":{"type":"Ident","pos":"1:13","Name":"x"}}],"Body":` +
+				`{"type":"ExprStmt","pos":"1:16","X":{"type":"BinaryExpr","pos":"1:16","X":` +
+				`{"type":"Ident","pos":"1:16","Name":"x"},"Op":"*","Y":{"type":"BasicLit",` +
+				`"pos":"1:20","Kind":"INT","Value":"2"}}}}}]}]}`,
+		},
+		{
+			src: `s = @"a""b\n"`,
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"ExprStmt","pos":"1:1","X":` +
+				`{"type":"AssignExpr","pos":"1:1","Lhs":{"type":"Ident","pos":"1:1","Name":"s"},` +
+				`"Op":"=","Rhs":{"type":"BasicLit","pos":"1:5","Kind":"STRING","Value":"a\"b\\n"}}}]}`,
+		},
+		{
+			src: "Foo.bar <- function(a, ...) {}",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"ExprStmt","pos":"1:1","X":` +
+				`{"type":"AssignExpr","pos":"1:1","Lhs":{"type":"SelectorExpr","pos":"1:1","X":` +
+				`{"type":"Ident","pos":"1:1","Name":"Foo"},"Sel":{"type":"Ident","pos":"1:5",` +
+				`"Name":"bar"}},"Op":"<-","Rhs":{"type":"FuncLit","pos":"1:12","Params":` +
+				`[{"type":"Param","pos":"1:21","Name":{"type":"Ident","pos":"1:21","Name":"a"}}],` +
+				`"Varargs":true,"Body":{"type":"BlockStmt","pos":"1:29"}}}}]}`,
+		},
+		{
+			src: "if (a) b() else c()",
+			json: `{"type":"Script","pos":"1:1","Stmts":[{"type":"IfStmt","pos":"1:1","Cond":` +
+				`{"type":"Ident","pos":"1:5","Name":"a"},"Then":{"type":"ExprStmt","pos":"1:8",` +
+				`"X":{"type":"CallExpr","pos":"1:8","Fun":{"type":"Ident","pos":"1:8","Name":"b"}}},` +
+				`"Else":{"type":"ExprStmt","pos":"1:17","X":{"type":"CallExpr","pos":"1:17",` +
+				`"Fun":{"type":"Ident","pos":"1:17","Name":"c"}}}}]}`,
+		},
+	} {
+		script, err := Parse([]byte(tc.src))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.src, err)
+			continue
+		}
+		data, err := MarshalJSON(script)
+		if err != nil {
+			t.Fatalf("%q: marshaling failed: %v", tc.src, err)
+		}
+		if got := string(data); got != tc.json {
+			t.Errorf("%q: got\n%s\nwant\n%s", tc.src, got, tc.json)
+		}
+	}
+}
+
+// shape lists the types and positions of the statements
+// and broken expressions of a syntax tree.
+type shape []string
+
+func (s *shape) Visit(n Node) Visitor {
+	switch n.(type) {
+	case nil, *Script:
+	case Stmt, *BadExpr:
+		name := strings.TrimPrefix(fmt.Sprintf("%T", n), "*squirrel.")
+		*s = append(*s, name+" "+n.Pos().String())
+	}
+	return s
+}
+
+func TestParseRecovery(t *testing.T) {
+	for _, tc := range []struct {
+		src   string
+		shape string
+		errs  []string
+	}{
+		{
+			src:   "a = 1\nb = 2",
+			shape: "ExprStmt 1:1, ExprStmt 2:1",
+		},
+		{
+			src:   "a = 1; b = 2",
+			shape: "ExprStmt 1:1, ExprStmt 1:8",
+		},
+		{
+			src:   "a = 1 b = 2\nc = 3",
+			shape: "BadStmt 1:1, ExprStmt 2:1",
+			errs:  []string{"1:7: expected ';' or newline, found IDENT b"},
+		},
+		{
+			src:   "x = 1 +",
+			shape: "BadStmt 1:1",
+			errs:  []string{"1:8: expected expression, found EOF"},
+		},
+		{
+			src:   "function f( {\n x = = 2\n y = 3 }",
+			shape: "FuncDecl 1:1, BlockStmt 1:13, BadStmt 2:2, ExprStmt 3:2",
+			errs: []string{
+				"1:13: expected identifier, found '{'",
+				"2:6: expected expression, found '='",
+			},
+		},
+		{
+			src:   "function f(a b) {\n return a\n}",
+			shape: "FuncDecl 1:1, BlockStmt 1:17, ReturnStmt 2:2",
+			errs:  []string{"1:14: expected ')', found IDENT b"},
+		},
+		{
+			src:   "local g = @(, x) x\nh()",
+			shape: "LocalStmt 1:1, ExprStmt 1:18, ExprStmt 2:1",
+			errs:  []string{"1:13: expected identifier, found ','"},
+		},
+		{
+			src:   "t = { a = , b = 2 }",
+			shape: "ExprStmt 1:1, BadExpr 1:7, BadExpr 1:7",
+			errs:  []string{"1:11: expected expression, found ','"},
+		},
+		{
+			src:   "class A {\n x = ;\n function f() {}\n}",
+			shape: "ClassDecl 1:1, BadExpr 2:2, BadExpr 2:2, BlockStmt 3:15",
+			errs:  []string{"2:6: expected expression, found ';'"},
+		},
+		{
+			src:   "while (a {\n}\nb()",
+			shape: "BadStmt 1:1, ExprStmt 3:1",
+			errs:  []string{"1:10: expected ')', found '{'"},
+		},
+		{
+			src:   "s = \"abc\nx = 1",
+			shape: "ExprStmt 1:1",
+			errs:  []string{"1:5: literal not terminated"},
+		},
+	} {
+		script, err := Parse([]byte(tc.src))
+		var s shape
+		Walk(&s, script)
+		if got := strings.Join(s, ", "); got != tc.shape {
+			t.Errorf("%q: got shape\n%s\nwant\n%s", tc.src, got, tc.shape)
+		}
+		errs := parseErrors(err)
+		if strings.Join(errs, "|") != strings.Join(tc.errs, "|") {
+			t.Errorf("%q: got errors %q, want %q", tc.src, errs, tc.errs)
+		}
+	}
+}
+
+func TestParseTooManyErrors(t *testing.T) {
+	src := strings.Repeat("= = =\n", 2*maxErrors)
+	_, err := Parse([]byte(src))
+	if n := len(parseErrors(err)); n != maxErrors+1 {
+		t.Errorf("got %d errors, want %d", n, maxErrors+1)
+	}
+}
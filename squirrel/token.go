@@ -0,0 +1,269 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+// Package squirrel tokenizes and parses the Squirrel 3 source
+// of the game scripts as decoded from the .bnut files.
+package squirrel
+
+import "fmt"
+
+// Pos is a position in the source. Line and Column start at 1,
+// columns are counted in bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// IsValid reports if the position is set.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+// Token is the kind of a lexical token.
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+
+	literalBeg
+	IDENT
+	INT
+	FLOAT
+	STRING
+	CHAR
+	literalEnd
+
+	operatorBeg
+	ADD        // +
+	SUB        // -
+	MUL        // *
+	QUO        // /
+	REM        // %
+	AND        // &
+	OR         // |
+	XOR        // ^
+	SHL        // <<
+	SHR        // >>
+	USHR       // >>>
+	LAND       // &&
+	LOR        // ||
+	NOT        // !
+	BNOT       // ~
+	EQL        // ==
+	NEQ        // !=
+	LSS        // <
+	GTR        // >
+	LEQ        // <=
+	GEQ        // >=
+	CMP        // <=>
+	ASSIGN     // =
+	NEWSLOT    // <-
+	ADD_ASSIGN // +=
+	SUB_ASSIGN // -=
+	MUL_ASSIGN // *=
+	QUO_ASSIGN // /=
+	REM_ASSIGN // %=
+	INC        // ++
+	DEC        // --
+	QUESTION   // ?
+	COLON      // :
+	DCOLON     // ::
+	PERIOD     // .
+	ELLIPSIS   // ...
+	COMMA      // ,
+	SEMICOLON  // ;
+	LPAREN     // (
+	RPAREN     // )
+	LBRACK     // [
+	RBRACK     // ]
+	LBRACE     // {
+	RBRACE     // }
+	AT         // @
+	ATTR_OPEN  // </
+	ATTR_CLOSE // />
+	operatorEnd
+
+	keywordBeg
+	BASE
+	BREAK
+	CASE
+	CATCH
+	CLASS
+	CLONE
+	CONST
+	CONSTRUCTOR
+	CONTINUE
+	DEFAULT
+	DELETE
+	DO
+	ELSE
+	ENUM
+	EXTENDS
+	FALSE
+	FILE
+	FOR
+	FOREACH
+	FUNCTION
+	IF
+	IN
+	INSTANCEOF
+	LINE
+	LOCAL
+	NULL
+	RESUME
+	RETURN
+	STATIC
+	SWITCH
+	THIS
+	THROW
+	TRUE
+	TRY
+	TYPEOF
+	WHILE
+	YIELD
+	keywordEnd
+)
+
+var tokens = [...]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+
+	IDENT:  "IDENT",
+	INT:    "INT",
+	FLOAT:  "FLOAT",
+	STRING: "STRING",
+	CHAR:   "CHAR",
+
+	ADD:        "+",
+	SUB:        "-",
+	MUL:        "*",
+	QUO:        "/",
+	REM:        "%",
+	AND:        "&",
+	OR:         "|",
+	XOR:        "^",
+	SHL:        "<<",
+	SHR:        ">>",
+	USHR:       ">>>",
+	LAND:       "&&",
+	LOR:        "||",
+	NOT:        "!",
+	BNOT:       "~",
+	EQL:        "==",
+	NEQ:        "!=",
+	LSS:        "<",
+	GTR:        ">",
+	LEQ:        "<=",
+	GEQ:        ">=",
+	CMP:        "<=>",
+	ASSIGN:     "=",
+	NEWSLOT:    "<-",
+	ADD_ASSIGN: "+=",
+	SUB_ASSIGN: "-=",
+	MUL_ASSIGN: "*=",
+	QUO_ASSIGN: "/=",
+	REM_ASSIGN: "%=",
+	INC:        "++",
+	DEC:        "--",
+	QUESTION:   "?",
+	COLON:      ":",
+	DCOLON:     "::",
+	PERIOD:     ".",
+	ELLIPSIS:   "...",
+	COMMA:      ",",
+	SEMICOLON:  ";",
+	LPAREN:     "(",
+	RPAREN:     ")",
+	LBRACK:     "[",
+	RBRACK:     "]",
+	LBRACE:     "{",
+	RBRACE:     "}",
+	AT:         "@",
+	ATTR_OPEN:  "</",
+	ATTR_CLOSE: "/>",
+
+	BASE:        "base",
+	BREAK:       "break",
+	CASE:        "case",
+	CATCH:       "catch",
+	CLASS:       "class",
+	CLONE:       "clone",
+	CONST:       "const",
+	CONSTRUCTOR: "constructor",
+	CONTINUE:    "continue",
+	DEFAULT:     "default",
+	DELETE:      "delete",
+	DO:          "do",
+	ELSE:        "else",
+	ENUM:        "enum",
+	EXTENDS:     "extends",
+	FALSE:       "false",
+	FILE:        "__FILE__",
+	FOR:         "for",
+	FOREACH:     "foreach",
+	FUNCTION:    "function",
+	IF:          "if",
+	IN:          "in",
+	INSTANCEOF:  "instanceof",
+	LINE:        "__LINE__",
+	LOCAL:       "local",
+	NULL:        "null",
+	RESUME:      "resume",
+	RETURN:      "return",
+	STATIC:      "static",
+	SWITCH:      "switch",
+	THIS:        "this",
+	THROW:       "throw",
+	TRUE:        "true",
+	TRY:         "try",
+	TYPEOF:      "typeof",
+	WHILE:       "while",
+	YIELD:       "yield",
+}
+
+func (tok Token) String() string {
+	if tok >= 0 && int(tok) < len(tokens) && tokens[tok] != "" {
+		return tokens[tok]
+	}
+	return fmt.Sprintf("token(%d)", int(tok))
+}
+
+// IsLiteral reports if the token is an identifier or a literal.
+func (tok Token) IsLiteral() bool { return literalBeg < tok && tok < literalEnd }
+
+// IsOperator reports if the token is an operator or a delimiter.
+func (tok Token) IsOperator() bool { return operatorBeg < tok && tok < operatorEnd }
+
+// IsKeyword reports if the token is a keyword.
+func (tok Token) IsKeyword() bool { return keywordBeg < tok && tok < keywordEnd }
+
+// MarshalText implements encoding.TextMarshaler.
+func (tok Token) MarshalText() ([]byte, error) {
+	return []byte(tok.String()), nil
+}
+
+var keywords map[string]Token
+
+func init() {
+	keywords = make(map[string]Token, keywordEnd-keywordBeg)
+	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
+		keywords[tokens[tok]] = tok
+	}
+}
+
+// Lookup returns the keyword token of ident or IDENT
+// if ident is not a keyword.
+func Lookup(ident string) Token {
+	if tok, ok := keywords[ident]; ok {
+		return tok
+	}
+	return IDENT
+}
@@ -0,0 +1,208 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package squirrel
+
+// Visitor is called by Walk for every node. If the returned
+// Visitor w is not nil the children of the node are walked
+// with w followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the syntax tree in depth-first order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Script:
+		walkStmts(v, n.Stmts)
+
+	case *BadExpr, *Ident, *BasicLit:
+
+	case *RootExpr:
+		Walk(v, n.Name)
+	case *ParenExpr:
+		Walk(v, n.X)
+	case *SelectorExpr:
+		Walk(v, n.X)
+		Walk(v, n.Sel)
+	case *IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+	case *CallExpr:
+		Walk(v, n.Fun)
+		walkExprs(v, n.Args)
+	case *UnaryExpr:
+		Walk(v, n.X)
+	case *PostfixExpr:
+		Walk(v, n.X)
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *AssignExpr:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+	case *CondExpr:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		Walk(v, n.Else)
+	case *ArrayLit:
+		walkExprs(v, n.Elems)
+	case *TableLit:
+		for _, s := range n.Slots {
+			Walk(v, s)
+		}
+	case *FuncLit:
+		for _, p := range n.Params {
+			Walk(v, p)
+		}
+		Walk(v, n.Body)
+	case *ClassLit:
+		if n.Extends != nil {
+			Walk(v, n.Extends)
+		}
+		if n.Attrs != nil {
+			Walk(v, n.Attrs)
+		}
+		for _, s := range n.Members {
+			Walk(v, s)
+		}
+
+	case *Slot:
+		if n.Attrs != nil {
+			Walk(v, n.Attrs)
+		}
+		Walk(v, n.Key)
+		Walk(v, n.Value)
+	case *Param:
+		Walk(v, n.Name)
+		if n.Default != nil {
+			Walk(v, n.Default)
+		}
+	case *Var:
+		Walk(v, n.Name)
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *CaseClause:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		walkStmts(v, n.Body)
+
+	case *BadStmt, *EmptyStmt, *BranchStmt:
+
+	case *ExprStmt:
+		Walk(v, n.X)
+	case *BlockStmt:
+		walkStmts(v, n.Stmts)
+	case *LocalStmt:
+		for _, x := range n.Vars {
+			Walk(v, x)
+		}
+	case *FuncDecl:
+		walkIdents(v, n.Name)
+		Walk(v, n.Func)
+	case *ClassDecl:
+		walkIdents(v, n.Name)
+		Walk(v, n.Class)
+	case *EnumDecl:
+		Walk(v, n.Name)
+		for _, m := range n.Members {
+			Walk(v, m)
+		}
+	case *ConstDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Value)
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *DoWhileStmt:
+		Walk(v, n.Body)
+		Walk(v, n.Cond)
+	case *ForStmt:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		walkExprs(v, n.Post)
+		Walk(v, n.Body)
+	case *ForeachStmt:
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Value)
+		Walk(v, n.X)
+		Walk(v, n.Body)
+	case *SwitchStmt:
+		Walk(v, n.Tag)
+		for _, c := range n.Cases {
+			Walk(v, c)
+		}
+	case *ReturnStmt:
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+	case *YieldStmt:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ThrowStmt:
+		Walk(v, n.X)
+	case *TryStmt:
+		Walk(v, n.Body)
+		Walk(v, n.Var)
+		Walk(v, n.Handler)
+	}
+
+	v.Visit(nil)
+}
+
+func walkStmts(v Visitor, list []Stmt) {
+	for _, s := range list {
+		Walk(v, s)
+	}
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkIdents(v Visitor, list []*Ident) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the syntax tree in depth-first order calling f
+// for every node. If f returns true the children of the node are
+// inspected followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,121 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Writer creates ggpack containers. The files are XOR encoded
+// with the given Method and written to Writer in the order
+// they are added. Close writes the directory and the header.
+type Writer struct {
+	Writer io.WriteSeeker
+	Method Method
+
+	pos    int64
+	files  []*Value
+	buf    []byte
+	closed bool
+}
+
+var errWriterClosed = errors.New("writer already closed")
+
+func (w *Writer) start() error {
+	if w.closed {
+		return errWriterClosed
+	}
+	if !w.Method.valid() {
+		return fmt.Errorf("unsupported method: %s", w.Method)
+	}
+	if w.pos == 0 {
+		// Reserve space for the header which is written by Close.
+		var header [8]byte
+		if _, err := w.Writer.Write(header[:]); err != nil {
+			return err
+		}
+		w.pos = int64(len(header))
+	}
+	return nil
+}
+
+func (w *Writer) write(data []byte) (int64, error) {
+	if int64(len(data)) > math.MaxInt32-w.pos {
+		return 0, errors.New("ggpack too large")
+	}
+	if cap(w.buf) >= len(data) {
+		w.buf = w.buf[:len(data)]
+	} else {
+		w.buf = make([]byte, len(data))
+	}
+	copy(w.buf, data)
+	EncodeXOR(w.buf, w.Method)
+	if _, err := w.Writer.Write(w.buf); err != nil {
+		return 0, err
+	}
+	ofs := w.pos
+	w.pos += int64(len(data))
+	return ofs, nil
+}
+
+// WriteFile adds a file with the given name and content to the container.
+// .bnut scripts are encoded with EncodeBnut and terminated by a NUL byte.
+// data is not modified.
+func (w *Writer) WriteFile(name string, data []byte) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if isBnut(name) {
+		data = EncodeBnut(data, 1)
+	}
+	ofs, err := w.write(data)
+	if err != nil {
+		return err
+	}
+	w.files = append(w.files, &Value{typ: HashType, hash: HashEntries{
+		{Key: "filename", Value: &Value{typ: StringType, str: name}},
+		{Key: "offset", Value: &Value{typ: IntegerType, integer: ofs}},
+		{Key: "size", Value: &Value{typ: IntegerType, integer: int64(len(data))}},
+	}})
+	return nil
+}
+
+// Close writes the directory of the added files and the header.
+// It does not close the underlying Writer.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	dir, err := EncodeDict(&Value{typ: HashType, hash: HashEntries{
+		{Key: "files", Value: &Value{typ: ArrayType, array: w.files}},
+	}})
+	if err != nil {
+		return err
+	}
+
+	ofs, err := w.write(dir)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Writer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	header := [...]int32{int32(ofs), int32(len(dir))}
+	if err := binary.Write(w.Writer, binary.LittleEndian, header[:]); err != nil {
+		return err
+	}
+	_, err = w.Writer.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,70 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePack creates a container with the given files in a
+// temporary directory and opens it for reading.
+func writePack(t *testing.T, method Method, files map[string][]byte) *Reader {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.ggpack1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	w := Writer{Writer: f, Method: method}
+	for name, data := range files {
+		if err := w.WriteFile(name, data); err != nil {
+			t.Fatalf("writing %s failed: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing writer failed: %v", err)
+	}
+	r := NewReaderAt(f)
+	if err := r.ReadPack(); err != nil {
+		t.Fatalf("reading pack failed: %v", err)
+	}
+	return r
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		"a.txt":    []byte("hello"),
+		"empty":    {},
+		"big.bin":  randomBytes(1000, 3),
+		"s.bnut":   randomScript(17, 4),
+		"d/x.json": []byte(`{"x": 1}`),
+	}
+	for _, method := range testMethods {
+		r := writePack(t, method, files)
+		if m := r.Method(); m != method.canonical() {
+			t.Errorf("%s: detected method %s", method, m)
+		}
+		for name, want := range files {
+			rd, err := r.Open(name)
+			if err != nil {
+				t.Fatalf("%s: opening %s failed: %v", method, name, err)
+			}
+			got, err := ioutil.ReadAll(rd)
+			if err != nil {
+				t.Fatalf("%s: reading %s failed: %v", method, name, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: content of %s differs", method, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"errors"
+	"io"
+)
+
+var magicBytes = [...]byte{
+	0x4f, 0xd0, 0xa0, 0xac,
+	0x4a, 0x5b, 0xb9, 0xe5,
+	0x93, 0x79, 0x45, 0xa5,
+	0xc1, 0xcb, 0x31, 0x93,
+}
+
+func xorCode(method Method) int {
+	if method != MethodAltKey {
+		return 0x6d
+	}
+	return 0xad
+}
+
+func xorPostPass(buf []byte, method Method) {
+	if method != MethodPlain {
+		for i := 5; i+1 < len(buf); i += 16 {
+			buf[i] ^= 0x0d
+			buf[i+1] ^= 0x0d
+		}
+	}
+}
+
+// DecodeXOR decodes a buffer in place which was XOR encoded
+// with the given method.
+func DecodeXOR(buf []byte, method Method) {
+	code := xorCode(method)
+	prev := byte(len(buf))
+	for i, v := range buf {
+		x := v ^ magicBytes[i&0xf] ^ byte(i*code)
+		buf[i] = x ^ prev
+		prev = x
+	}
+	xorPostPass(buf, method)
+}
+
+// EncodeXOR encodes a buffer in place with the given method.
+// It is the inverse of DecodeXOR.
+func EncodeXOR(buf []byte, method Method) {
+	// The post pass is its own inverse so it has to be undone first.
+	xorPostPass(buf, method)
+	code := xorCode(method)
+	prev := byte(len(buf))
+	for i, v := range buf {
+		x := v ^ prev
+		buf[i] = x ^ magicBytes[i&0xf] ^ byte(i*code)
+		prev = x
+	}
+}
+
+type entryReader struct {
+	r      io.ReaderAt
+	off    int64
+	size   int64
+	method Method
+	code   int
+	pos    int64
+	// prev caches the state of Read for position prevPos,
+	// -1 if unknown. ReadAt does not use it.
+	prev    byte
+	prevPos int64
+}
+
+// ReadSeekerAt is implemented by the decoding readers
+// to allow them to be stacked on each other.
+type ReadSeekerAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// NewEntryReader returns a reader which XOR decodes the entry
+// of the given size at offset off in r on the fly. This is the
+// streaming counterpart of Reader.DecodeXOR. The decoder only
+// depends on the position inside the entry and the preceding
+// encoded byte so seeking is supported, too. ReadAt may be
+// called in parallel if r allows it.
+func NewEntryReader(r io.ReaderAt, off, size int64, method Method) ReadSeekerAt {
+	return &entryReader{
+		r:       r,
+		off:     off,
+		size:    size,
+		method:  method,
+		code:    xorCode(method),
+		prevPos: -1,
+	}
+}
+
+// prevAt returns the state needed to decode the byte at position pos.
+// It does not touch the cache of Read so it is safe for parallel use.
+func (er *entryReader) prevAt(pos int64) (byte, error) {
+	if pos == 0 {
+		return byte(er.size), nil
+	}
+	var b [1]byte
+	if _, err := er.r.ReadAt(b[:], er.off+pos-1); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	i := pos - 1
+	return b[0] ^ magicBytes[i&0xf] ^ byte(int(i)*er.code), nil
+}
+
+// decode decodes the bytes at position pos < size into p.
+// prev is the state for pos. The state for the position
+// behind the decoded bytes is returned.
+func (er *entryReader) decode(p []byte, pos int64, prev byte) (int, byte, error) {
+	if rest := er.size - pos; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := er.r.ReadAt(p, er.off+pos)
+	if n < len(p) && err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	for j, v := range p[:n] {
+		i := pos + int64(j)
+		x := v ^ magicBytes[i&0xf] ^ byte(int(i)*er.code)
+		v = x ^ prev
+		prev = x
+		if er.method != MethodPlain {
+			switch i & 0xf {
+			case 5:
+				if i+1 < er.size {
+					v ^= 0x0d
+				}
+			case 6:
+				v ^= 0x0d
+			}
+		}
+		p[j] = v
+	}
+	if n == len(p) {
+		err = nil
+	}
+	return n, prev, err
+}
+
+func (er *entryReader) Read(p []byte) (int, error) {
+	if er.pos >= er.size {
+		return 0, io.EOF
+	}
+	prev := er.prev
+	if er.pos != er.prevPos {
+		var err error
+		if prev, err = er.prevAt(er.pos); err != nil {
+			return 0, err
+		}
+	}
+	n, prev, err := er.decode(p, er.pos, prev)
+	er.pos += int64(n)
+	er.prev, er.prevPos = prev, er.pos
+	return n, err
+}
+
+func (er *entryReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= er.size {
+		return 0, io.EOF
+	}
+	prev, err := er.prevAt(off)
+	if err != nil {
+		return 0, err
+	}
+	n, _, err := er.decode(p, off, prev)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (er *entryReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += er.pos
+	case io.SeekEnd:
+		offset += er.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	er.pos = offset
+	return offset, nil
+}
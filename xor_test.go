@@ -0,0 +1,170 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package ggpack
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+var (
+	testSizes = []int{0, 1, 5, 6, 7, 16, 17, 22, 1000}
+	// Method 1 is included as it is accepted as input.
+	testMethods = []Method{MethodPlain, 1, MethodAltKey, MethodDefault}
+)
+
+func randomBytes(n int, seed int64) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// checkSeeks reads from r after seeking to several positions
+// and compares the results with want.
+func checkSeeks(t *testing.T, r io.ReadSeeker, want []byte) {
+	t.Helper()
+	size := int64(len(want))
+	seeks := []struct {
+		offset int64
+		whence int
+	}{
+		{size / 2, io.SeekStart},
+		{0, io.SeekStart},
+		{-1, io.SeekEnd},
+		{-size, io.SeekEnd},
+		{size / 3, io.SeekStart},
+		{1, io.SeekCurrent},
+		{-2, io.SeekCurrent},
+		{size, io.SeekStart},
+		{size + 5, io.SeekStart},
+	}
+	var pos int64
+	for _, s := range seeks {
+		next := s.offset
+		switch s.whence {
+		case io.SeekCurrent:
+			next += pos
+		case io.SeekEnd:
+			next += size
+		}
+		if next < 0 {
+			continue
+		}
+		got, err := r.Seek(s.offset, s.whence)
+		if err != nil || got != next {
+			t.Fatalf("seek(%d, %d) = %d, %v, want %d",
+				s.offset, s.whence, got, err, next)
+		}
+		var buf [7]byte
+		n, err := io.ReadFull(r, buf[:])
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			t.Fatalf("read at %d failed: %v", next, err)
+		}
+		var expect []byte
+		if next < size {
+			expect = want[next:]
+			if len(expect) > len(buf) {
+				expect = expect[:len(buf)]
+			}
+		}
+		if !bytes.Equal(buf[:n], expect) {
+			t.Fatalf("read at %d: got %x, want %x", next, buf[:n], expect)
+		}
+		pos = next + int64(n)
+	}
+}
+
+// checkParallelReadAt reads r concurrently at random offsets
+// and compares the results with want. Run with -race.
+func checkParallelReadAt(t *testing.T, r ReadSeekerAt, want []byte) {
+	t.Helper()
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan string, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			<-start
+			for i := 0; i < 2000; i++ {
+				off := rnd.Intn(len(want))
+				buf := make([]byte, rnd.Intn(len(want)-off)+1)
+				n, err := r.ReadAt(buf, int64(off))
+				if n != len(buf) || err != nil && off+n != len(want) {
+					errs <- "short read"
+					return
+				}
+				if !bytes.Equal(buf, want[off:off+n]) {
+					errs <- "wrong data"
+					return
+				}
+			}
+		}(int64(g))
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
+
+func TestXORRoundTrip(t *testing.T) {
+	for _, method := range testMethods {
+		for _, size := range testSizes {
+			data := randomBytes(size, int64(size))
+			buf := append([]byte(nil), data...)
+			EncodeXOR(buf, method)
+			if size > 0 && bytes.Equal(buf, data) {
+				t.Errorf("%s/%d: encoding did not change data", method, size)
+			}
+			DecodeXOR(buf, method)
+			if !bytes.Equal(buf, data) {
+				t.Errorf("%s/%d: round trip failed", method, size)
+			}
+		}
+	}
+}
+
+func TestEntryReader(t *testing.T) {
+	for _, method := range testMethods {
+		for _, size := range testSizes {
+			data := randomBytes(size, int64(size))
+			// Place the entry behind some other data.
+			enc := append(randomBytes(3, 0), data...)
+			EncodeXOR(enc[3:], method)
+			er := NewEntryReader(bytes.NewReader(enc), 3, int64(size), method)
+			got, err := ioutil.ReadAll(er)
+			if err != nil {
+				t.Fatalf("%s/%d: reading failed: %v", method, size, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("%s/%d: decoded data differs", method, size)
+			}
+			checkSeeks(t, er, data)
+		}
+	}
+}
+
+func TestEntryReaderParallelReadAt(t *testing.T) {
+	want := randomBytes(1000, 1)
+	enc := append([]byte(nil), want...)
+	EncodeXOR(enc, MethodDefault)
+	er := NewEntryReader(bytes.NewReader(enc), 0, int64(len(enc)), MethodDefault)
+	// Interleave a Read to fill the cache used by Read.
+	var first [10]byte
+	if _, err := er.Read(first[:]); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	checkParallelReadAt(t, er, want)
+}
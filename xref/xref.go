@@ -0,0 +1,527 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+// Package xref builds a cross-reference index of the definitions
+// and references of global symbols in the game scripts.
+package xref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/s-l-teichmann/ggpack/squirrel"
+)
+
+// Kind is the kind of a definition.
+type Kind int
+
+const (
+	// Function is a global function.
+	Function Kind = iota
+	// Method is a function stored in a class or table.
+	Method
+	// Class is a class.
+	Class
+	// Room is a global table with a background slot.
+	Room
+	// Object is a table stored in a room.
+	Object
+	// Table is any other table stored globally or in a table.
+	Table
+	// Global is any other global variable, constant or enumeration.
+	Global
+)
+
+var kindNames = [...]string{
+	Function: "function",
+	Method:   "method",
+	Class:    "class",
+	Room:     "room",
+	Object:   "object",
+	Table:    "table",
+	Global:   "global",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("unknown (%d)", int(k))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Location is a position in a script.
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// Definition is the definition of a symbol. Symbols of slots
+// are qualified with the names of their tables like Bank.enter.
+type Definition struct {
+	Symbol   string   `json:"symbol"`
+	Kind     Kind     `json:"kind"`
+	Location Location `json:"location"`
+}
+
+// Reference is a use of a symbol.
+type Reference struct {
+	Symbol string `json:"symbol"`
+	// From is the symbol of the innermost definition the reference
+	// is found in. It is empty on the top level of a script.
+	From string `json:"from,omitempty"`
+	// Call reports if the symbol is called.
+	Call     bool     `json:"call,omitempty"`
+	Location Location `json:"location"`
+}
+
+// Index is a cross-reference index over scripts.
+type Index struct {
+	defs []*Definition
+	refs []*Reference
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{}
+}
+
+// Add adds the definitions and references of a script.
+func (x *Index) Add(file string, script *squirrel.Script) {
+	c := collector{
+		index:  x,
+		file:   file,
+		frames: map[squirrel.Node]*frame{},
+		skip:   map[*squirrel.Ident]bool{},
+		calls:  map[squirrel.Node]bool{},
+	}
+	squirrel.Inspect(script, c.visit)
+}
+
+// Definitions returns the definitions of the symbol.
+func (x *Index) Definitions(symbol string) []*Definition {
+	var defs []*Definition
+	for _, d := range x.defs {
+		if d.Symbol == symbol {
+			defs = append(defs, d)
+		}
+	}
+	return defs
+}
+
+// References returns the references to the symbol.
+func (x *Index) References(symbol string) []*Reference {
+	var refs []*Reference
+	for _, r := range x.refs {
+		if r.Symbol == symbol {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// Symbols returns the sorted names of the defined symbols.
+func (x *Index) Symbols() []string {
+	seen := map[string]bool{}
+	var symbols []string
+	for _, d := range x.defs {
+		if !seen[d.Symbol] {
+			seen[d.Symbol] = true
+			symbols = append(symbols, d.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Edge is a connection in the symbol graph from the definition
+// a reference is found in to the referenced symbol.
+type Edge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+	Calls int    `json:"calls"`
+}
+
+// Graph is the symbol graph of an index.
+type Graph struct {
+	Definitions []*Definition `json:"definitions"`
+	References  []*Reference  `json:"references"`
+	Edges       []*Edge       `json:"edges"`
+}
+
+// Graph returns the symbol graph. References on the top level
+// of scripts have no edges.
+func (x *Index) Graph() *Graph {
+	g := &Graph{
+		Definitions: append([]*Definition{}, x.defs...),
+		References:  append([]*Reference{}, x.refs...),
+		Edges:       []*Edge{},
+	}
+	type key struct{ from, to string }
+	edges := map[key]*Edge{}
+	for _, r := range x.refs {
+		if r.From == "" {
+			continue
+		}
+		k := key{r.From, r.Symbol}
+		e := edges[k]
+		if e == nil {
+			e = &Edge{From: r.From, To: r.Symbol}
+			edges[k] = e
+			g.Edges = append(g.Edges, e)
+		}
+		e.Count++
+		if r.Call {
+			e.Calls++
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// frame is a definition the collector is in.
+type frame struct {
+	symbol string
+	kind   Kind
+	node   squirrel.Node
+	// owner is the symbol this refers to in the definition.
+	owner string
+}
+
+// collector collects the definitions and references
+// while inspecting a script.
+type collector struct {
+	index *Index
+	file  string
+
+	nodes []squirrel.Node
+	// stack holds the frames entered by the nodes or nil.
+	stack []*frame
+	// frames are the definitions to be entered
+	// when reaching their nodes.
+	frames map[squirrel.Node]*frame
+	// scopes are the local names of the functions.
+	scopes []map[string]bool
+	skip   map[*squirrel.Ident]bool
+	calls  map[squirrel.Node]bool
+}
+
+func (c *collector) location(pos squirrel.Pos) Location {
+	return Location{File: c.file, Line: pos.Line, Column: pos.Column}
+}
+
+func (c *collector) current() *frame {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i] != nil {
+			return c.stack[i]
+		}
+	}
+	return nil
+}
+
+func (c *collector) parent() squirrel.Node {
+	if len(c.nodes) < 2 {
+		return nil
+	}
+	return c.nodes[len(c.nodes)-2]
+}
+
+func (c *collector) isLocal(name string) bool {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if c.scopes[i][name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *collector) declare(ids ...*squirrel.Ident) {
+	scope := c.scopes[len(c.scopes)-1]
+	for _, id := range ids {
+		if id != nil {
+			scope[id.Name] = true
+			c.skip[id] = true
+		}
+	}
+}
+
+// kindOf returns the kind of a definition by its value.
+func kindOf(value squirrel.Expr, parent *frame) Kind {
+	switch v := value.(type) {
+	case *squirrel.FuncLit:
+		if parent != nil {
+			return Method
+		}
+		return Function
+	case *squirrel.ClassLit:
+		return Class
+	case *squirrel.TableLit:
+		switch {
+		case parent == nil && hasSlot(v, "background"):
+			return Room
+		case parent != nil && parent.kind == Room:
+			return Object
+		}
+		return Table
+	}
+	return Global
+}
+
+func hasSlot(t *squirrel.TableLit, name string) bool {
+	for _, s := range t.Slots {
+		if id, ok := s.Key.(*squirrel.Ident); ok && id.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// define records a definition. If value is a function, class or
+// table it is entered as frame when it is reached.
+func (c *collector) define(name []string, pos squirrel.Pos, value squirrel.Expr, parent *frame) {
+	symbol := strings.Join(name, ".")
+	kind := kindOf(value, parent)
+	if parent == nil && len(name) > 1 && kind == Function {
+		kind = Method
+	}
+	c.index.defs = append(c.index.defs, &Definition{
+		Symbol:   symbol,
+		Kind:     kind,
+		Location: c.location(pos),
+	})
+	if value == nil {
+		return
+	}
+	f := &frame{symbol: symbol, kind: kind, node: value}
+	switch kind {
+	case Function, Method:
+		f.owner = strings.Join(name[:len(name)-1], ".")
+	case Global:
+		return
+	default:
+		f.owner = symbol
+	}
+	c.frames[value] = f
+}
+
+func (c *collector) reference(symbol string, pos squirrel.Pos, call bool) {
+	r := &Reference{Symbol: symbol, Call: call, Location: c.location(pos)}
+	if f := c.current(); f != nil {
+		r.From = f.symbol
+	}
+	c.index.refs = append(c.index.refs, r)
+}
+
+func (c *collector) visit(node squirrel.Node) bool {
+
+	if node == nil {
+		c.nodes = c.nodes[:len(c.nodes)-1]
+		if n := len(c.stack) - 1; c.stack[n] != nil {
+			if _, ok := c.stack[n].node.(*squirrel.FuncLit); ok {
+				c.scopes = c.scopes[:len(c.scopes)-1]
+			}
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+		return false
+	}
+
+	c.nodes = append(c.nodes, node)
+	f := c.frames[node]
+	c.stack = append(c.stack, f)
+
+	switch n := node.(type) {
+	case *squirrel.Script:
+		c.scopes = append(c.scopes, map[string]bool{})
+
+	case *squirrel.FuncLit:
+		scope := map[string]bool{}
+		if f == nil {
+			// Anonymous functions get a frame of their own
+			// to keep track of their scope.
+			cur := c.current()
+			f = &frame{node: n}
+			if cur != nil {
+				f.symbol, f.owner = cur.symbol, cur.owner
+			}
+			c.stack[len(c.stack)-1] = f
+		}
+		c.scopes = append(c.scopes, scope)
+		for _, p := range n.Params {
+			c.declare(p.Name)
+		}
+
+	case *squirrel.LocalStmt:
+		for _, v := range n.Vars {
+			c.declare(v.Name)
+		}
+
+	case *squirrel.ForeachStmt:
+		c.declare(n.Key, n.Value)
+
+	case *squirrel.TryStmt:
+		c.declare(n.Var)
+
+	case *squirrel.FuncDecl:
+		names := make([]string, len(n.Name))
+		for i, id := range n.Name {
+			names[i] = id.Name
+			c.skip[id] = true
+		}
+		c.define(names, n.Name[len(n.Name)-1].Pos(), n.Func, nil)
+
+	case *squirrel.ClassDecl:
+		names := make([]string, len(n.Name))
+		for i, id := range n.Name {
+			names[i] = id.Name
+			c.skip[id] = true
+		}
+		c.define(names, n.Name[len(n.Name)-1].Pos(), n.Class, nil)
+
+	case *squirrel.EnumDecl:
+		c.skip[n.Name] = true
+		c.define([]string{n.Name.Name}, n.Name.Pos(), nil, nil)
+		for _, m := range n.Members {
+			c.skip[m.Name] = true
+		}
+
+	case *squirrel.ConstDecl:
+		c.skip[n.Name] = true
+		c.define([]string{n.Name.Name}, n.Name.Pos(), nil, nil)
+
+	case *squirrel.AssignExpr:
+		if n.Op != squirrel.NEWSLOT {
+			break
+		}
+		var name *squirrel.Ident
+		switch lhs := n.Lhs.(type) {
+		case *squirrel.Ident:
+			if len(c.scopes) == 1 && !c.isLocal(lhs.Name) {
+				name = lhs
+			}
+		case *squirrel.RootExpr:
+			name = lhs.Name
+		}
+		if name != nil {
+			c.skip[name] = true
+			c.define([]string{name.Name}, name.Pos(), n.Rhs, nil)
+		}
+
+	case *squirrel.Param:
+		c.skip[n.Name] = true
+
+	case *squirrel.Slot:
+		if k, ok := n.Key.(*squirrel.Ident); ok {
+			c.skip[k] = true
+		}
+		parent := c.current()
+		if parent == nil || parent.node != c.parent() {
+			break
+		}
+		var key string
+		switch k := n.Key.(type) {
+		case *squirrel.Ident:
+			key = k.Name
+		case *squirrel.BasicLit:
+			if k.Kind != squirrel.STRING {
+				return true
+			}
+			key = k.Value
+		default:
+			return true
+		}
+		switch n.Value.(type) {
+		case *squirrel.FuncLit, *squirrel.TableLit, *squirrel.ClassLit:
+			c.define([]string{parent.symbol, key}, n.Key.Pos(), n.Value, parent)
+		}
+
+	case *squirrel.CallExpr:
+		c.calls[n.Fun] = true
+
+	case *squirrel.SelectorExpr:
+		if sel, ok := c.parent().(*squirrel.SelectorExpr); ok && sel.X == node {
+			// Part of a longer chain.
+			break
+		}
+		c.chain(n)
+
+	case *squirrel.RootExpr:
+		c.skip[n.Name] = true
+		c.reference(n.Name.Name, n.Name.Pos(), c.calls[node])
+
+	case *squirrel.Ident:
+		if c.skip[n] || c.isLocal(n.Name) {
+			break
+		}
+		switch n.Name {
+		case "this", "base", "__LINE__", "__FILE__":
+			break
+		default:
+			c.reference(n.Name, n.Pos(), c.calls[node])
+		}
+	}
+
+	return true
+}
+
+// chain records the references of a selector chain like a.b.c.
+// Chains starting with this are resolved to the table or class
+// the current function is stored in.
+func (c *collector) chain(top *squirrel.SelectorExpr) {
+
+	var sels []*squirrel.Ident
+	var x squirrel.Expr = top
+	for {
+		sel, ok := x.(*squirrel.SelectorExpr)
+		if !ok {
+			break
+		}
+		sels = append(sels, sel.Sel)
+		c.skip[sel.Sel] = true
+		x = sel.X
+	}
+
+	var prefix []string
+	switch root := x.(type) {
+	case *squirrel.Ident:
+		switch {
+		case root.Name == "this":
+			if f := c.current(); f != nil && f.owner != "" {
+				prefix = strings.Split(f.owner, ".")
+			}
+		case root.Name == "base" || c.isLocal(root.Name):
+			return
+		default:
+			prefix = []string{root.Name}
+		}
+	case *squirrel.RootExpr:
+		prefix = []string{root.Name.Name}
+	default:
+		return
+	}
+
+	for i := len(sels) - 1; i >= 0; i-- {
+		prefix = append(prefix, sels[i].Name)
+		call := i == 0 && c.calls[top]
+		c.reference(strings.Join(prefix, "."), sels[i].Pos(), call)
+	}
+}
@@ -0,0 +1,125 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package xref
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/s-l-teichmann/ggpack/squirrel"
+)
+
+const testScript = `Bank <- {
+	background = "Bank"
+	door = { name = "door" }
+	enter = function() {
+		this.door.open()
+		helper(1)
+	}
+}
+function Foo::bar(x) {
+	local helper = 1
+	helper + x
+	Bank.enter()
+	::helper()
+	foreach (i, count in x) { count }
+}
+function helper(y) { return y + count }
+count <- 0
+`
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	script, err := squirrel.Parse([]byte(testScript))
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	x := New()
+	x.Add("test.nut", script)
+	return x
+}
+
+func checkLines(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s: got\n%s\nwant\n%s", what,
+			strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	x := testIndex(t)
+	var got []string
+	for _, symbol := range x.Symbols() {
+		for _, d := range x.Definitions(symbol) {
+			got = append(got, fmt.Sprintf("%s %s %s", d.Symbol, d.Kind, d.Location))
+		}
+	}
+	checkLines(t, "definitions", got, []string{
+		"Bank room test.nut:1:1",
+		"Bank.door object test.nut:3:2",
+		"Bank.enter method test.nut:4:2",
+		"Foo.bar method test.nut:9:15",
+		"count global test.nut:17:1",
+		"helper function test.nut:16:10",
+	})
+}
+
+func TestReferences(t *testing.T) {
+	x := testIndex(t)
+	for _, tc := range []struct {
+		symbol string
+		want   []string
+	}{
+		// this is resolved to the table the method is stored in.
+		{"Bank.door", []string{"Bank.enter test.nut:5:8"}},
+		{"Bank.door.open", []string{"Bank.enter call test.nut:5:13"}},
+		// The local helper in Foo::bar shadows the global one
+		// but ::helper does not.
+		{"helper", []string{
+			"Bank.enter call test.nut:6:3",
+			"Foo.bar call test.nut:13:4",
+		}},
+		{"Bank.enter", []string{"Foo.bar call test.nut:12:7"}},
+		// The foreach variable shadows the global count.
+		{"count", []string{"helper test.nut:16:33"}},
+		{"x", nil},
+		{"y", nil},
+	} {
+		var got []string
+		for _, r := range x.References(tc.symbol) {
+			s := r.From
+			if r.Call {
+				s += " call"
+			}
+			got = append(got, s+" "+r.Location.String())
+		}
+		checkLines(t, tc.symbol, got, tc.want)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	g := testIndex(t).Graph()
+	if len(g.Definitions) != 6 {
+		t.Errorf("got %d definitions, want 6", len(g.Definitions))
+	}
+	var got []string
+	for _, e := range g.Edges {
+		got = append(got, fmt.Sprintf("%s -> %s %d/%d", e.From, e.To, e.Count, e.Calls))
+	}
+	checkLines(t, "edges", got, []string{
+		"Bank.enter -> Bank.door 1/0",
+		"Bank.enter -> Bank.door.open 1/1",
+		"Bank.enter -> helper 1/1",
+		"Foo.bar -> Bank 1/0",
+		"Foo.bar -> Bank.enter 1/1",
+		"Foo.bar -> helper 1/1",
+		"helper -> count 1/0",
+	})
+}
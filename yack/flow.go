@@ -0,0 +1,112 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package yack
+
+const (
+	// StartLabel is the label a dialog starts at by default.
+	StartLabel = "start"
+	// ExitLabel is the pseudo label which ends a dialog.
+	ExitLabel = "exit"
+)
+
+// Flow is the control flow between the labels of a dialog.
+type Flow struct {
+	Dialog *Dialog
+	// Start is the label the flow starts at. It is the
+	// StartLabel or the first label if there is none.
+	Start *Label
+	// Unreachable are the labels which cannot be reached from
+	// Start by gotos, choices and overrides. Labels entered
+	// directly from scripts show up here, too.
+	Unreachable []*Label
+	// DeadEnds are the choices leading to an undefined label
+	// or to a label which neither offers choices nor continues
+	// with a goto.
+	DeadEnds []*Stmt
+}
+
+// Target returns the label a goto, choice or override jumps to.
+func (s *Stmt) Target() (string, bool) {
+	switch c := s.Cmd.(type) {
+	case *Goto:
+		return c.Label, true
+	case *Choice:
+		return c.Label, true
+	case *Override:
+		return c.Label, true
+	}
+	return "", false
+}
+
+// continues reports if a label offers choices or jumps somewhere.
+func (l *Label) continues() bool {
+	for _, s := range l.Stmts {
+		switch s.Cmd.(type) {
+		case *Goto, *Choice:
+			return true
+		}
+	}
+	return false
+}
+
+// NewFlow analyses the control flow of a dialog.
+func NewFlow(d *Dialog) *Flow {
+	f := &Flow{Dialog: d}
+	if f.Start = d.Label(StartLabel); f.Start == nil && len(d.Labels) > 0 {
+		f.Start = d.Labels[0]
+	}
+
+	reached := map[*Label]bool{}
+	var visit func(*Label)
+	visit = func(l *Label) {
+		if l == nil || reached[l] {
+			return
+		}
+		reached[l] = true
+		for _, s := range l.Stmts {
+			if target, ok := s.Target(); ok {
+				visit(d.Label(target))
+			}
+		}
+	}
+	visit(f.Start)
+
+	for _, l := range d.Labels {
+		if !reached[l] {
+			f.Unreachable = append(f.Unreachable, l)
+		}
+		for _, s := range l.Stmts {
+			if c, ok := s.Cmd.(*Choice); ok && c.Label != ExitLabel {
+				if target := d.Label(c.Label); target == nil || !target.continues() {
+					f.DeadEnds = append(f.DeadEnds, s)
+				}
+			}
+		}
+	}
+	return f
+}
+
+// IsUnreachable reports if the label cannot be reached.
+func (f *Flow) IsUnreachable(l *Label) bool {
+	for _, u := range f.Unreachable {
+		if u == l {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeadEnd reports if the statement is a dead-end choice.
+func (f *Flow) IsDeadEnd(s *Stmt) bool {
+	for _, d := range f.DeadEnds {
+		if d == s {
+			return true
+		}
+	}
+	return false
+}
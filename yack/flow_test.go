@@ -0,0 +1,153 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package yack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlow(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		src         string
+		start       string
+		unreachable []string
+		// deadEnds are the positions of the dead-end choices.
+		deadEnds []string
+	}{
+		{
+			name:  "empty",
+			src:   "",
+			start: "",
+		},
+		{
+			name: "start label",
+			src: ":intro\n" +
+				"-> start\n" +
+				":start\n" +
+				"1 \"Hi\" -> talk\n" +
+				"2 \"Bye\" -> exit\n" +
+				":talk\n" +
+				"-> start\n",
+			start:       "start",
+			unreachable: []string{"intro"},
+		},
+		{
+			name: "first label without start",
+			src: ":main\n" +
+				"1 \"Go on\" -> more\n" +
+				":more\n" +
+				"-> main\n" +
+				":orphan\n" +
+				"-> main\n",
+			start:       "main",
+			unreachable: []string{"orphan"},
+		},
+		{
+			name: "undefined targets",
+			src: ":main\n" +
+				"1 \"Where?\" -> nowhere\n" +
+				"-> missing\n" +
+				"2 \"Done\" -> exit\n",
+			start:    "main",
+			deadEnds: []string{"2:1"},
+		},
+		{
+			name: "labels ending without continuing",
+			src: ":main\n" +
+				"1 \"Talk\" -> talk\n" +
+				"2 \"Code\" -> code [once]\n" +
+				"3 \"Loop\" -> main\n" +
+				":talk\n" +
+				"ray: \"Nothing more.\"\n" +
+				":code\n" +
+				"!g.x = 1\n" +
+				"override main\n",
+			start:    "main",
+			deadEnds: []string{"2:1", "3:1"},
+		},
+		{
+			name: "override reaches labels",
+			src: ":main\n" +
+				"override hidden\n" +
+				"-> exit\n" +
+				":hidden\n" +
+				"shutup\n" +
+				":lost\n" +
+				"-> hidden\n",
+			start:       "main",
+			unreachable: []string{"lost"},
+		},
+	} {
+		d, err := Parse([]byte(tc.src))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		f := NewFlow(d)
+
+		start := ""
+		if f.Start != nil {
+			start = f.Start.Name
+		}
+		if start != tc.start {
+			t.Errorf("%s: start %q, want %q", tc.name, start, tc.start)
+		}
+
+		var unreachable []string
+		for _, l := range d.Labels {
+			if f.IsUnreachable(l) {
+				unreachable = append(unreachable, l.Name)
+			}
+		}
+		if got, want := strings.Join(unreachable, ","), strings.Join(tc.unreachable, ","); got != want {
+			t.Errorf("%s: unreachable %q, want %q", tc.name, got, want)
+		}
+		if len(f.Unreachable) != len(unreachable) {
+			t.Errorf("%s: %d unreachable labels, IsUnreachable found %d",
+				tc.name, len(f.Unreachable), len(unreachable))
+		}
+
+		var deadEnds []string
+		for _, l := range d.Labels {
+			for _, s := range l.Stmts {
+				if f.IsDeadEnd(s) {
+					deadEnds = append(deadEnds, s.Pos.String())
+				}
+			}
+		}
+		if got, want := strings.Join(deadEnds, ","), strings.Join(tc.deadEnds, ","); got != want {
+			t.Errorf("%s: dead ends %q, want %q", tc.name, got, want)
+		}
+		if len(f.DeadEnds) != len(deadEnds) {
+			t.Errorf("%s: %d dead ends, IsDeadEnd found %d",
+				tc.name, len(f.DeadEnds), len(deadEnds))
+		}
+	}
+}
+
+func TestFlowForeignNodes(t *testing.T) {
+	d, err := Parse([]byte(":main\n1 \"x\" -> nowhere\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFlow(d)
+	other, err := Parse([]byte(":main\n1 \"x\" -> nowhere\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.IsUnreachable(other.Labels[0]) {
+		t.Error("label of another dialog reported as unreachable")
+	}
+	if f.IsDeadEnd(other.Labels[0].Stmts[0]) {
+		t.Error("choice of another dialog reported as dead end")
+	}
+	if !f.IsDeadEnd(d.Labels[0].Stmts[0]) {
+		t.Error("choice to undefined label not reported as dead end")
+	}
+}
@@ -0,0 +1,429 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package yack
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	dialog *Dialog
+	label  *Label
+	labels map[string]*Label
+	errors ErrorList
+
+	// the current line
+	line   []byte
+	offset int // offset of the line in the source
+	lineNo int
+	i      int
+}
+
+// Parse parses a yack script. Lines which cannot be parsed are
+// reported in the returned ErrorList and skipped, so the dialog
+// is usable even if the error is not nil.
+func Parse(src []byte) (*Dialog, error) {
+	p := &parser{
+		dialog: &Dialog{},
+		labels: map[string]*Label{},
+	}
+	for offset, lineNo := 0, 1; offset < len(src); lineNo++ {
+		end := bytes.IndexByte(src[offset:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += offset
+		}
+		p.line = bytes.TrimRight(src[offset:end], "\r")
+		p.offset, p.lineNo, p.i = offset, lineNo, 0
+		p.parseLine()
+		offset = end + 1
+	}
+	return p.dialog, p.errors.Err()
+}
+
+func (p *parser) pos(i int) Pos {
+	return Pos{Offset: p.offset + i, Line: p.lineNo, Column: i + 1}
+}
+
+func (p *parser) errorf(i int, format string, args ...interface{}) {
+	p.errors = append(p.errors, &Error{Pos: p.pos(i), Msg: fmt.Sprintf(format, args...)})
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' }
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.line) && isSpace(p.line[p.i]) {
+		p.i++
+	}
+}
+
+func (p *parser) eol() bool {
+	p.skipSpace()
+	return p.i >= len(p.line)
+}
+
+// ident scans an identifier and returns its start.
+func (p *parser) ident() (string, int) {
+	p.skipSpace()
+	start := p.i
+	for p.i < len(p.line) && isIdent(p.line[p.i]) {
+		p.i++
+	}
+	return string(p.line[start:p.i]), start
+}
+
+// word scans everything up to the next white space.
+func (p *parser) word() (string, int) {
+	p.skipSpace()
+	start := p.i
+	for p.i < len(p.line) && !isSpace(p.line[p.i]) {
+		p.i++
+	}
+	return string(p.line[start:p.i]), start
+}
+
+// rest returns the trimmed rest of the line.
+func (p *parser) rest() (string, int) {
+	p.skipSpace()
+	start := p.i
+	p.i = len(p.line)
+	return string(bytes.TrimSpace(p.line[start:])), start
+}
+
+// str scans a quoted string.
+func (p *parser) str() (string, bool) {
+	p.skipSpace()
+	if p.i >= len(p.line) || p.line[p.i] != '"' {
+		p.errorf(p.i, "expected string")
+		return "", false
+	}
+	start := p.i
+	var b strings.Builder
+	for p.i++; p.i < len(p.line); p.i++ {
+		switch c := p.line[p.i]; c {
+		case '"':
+			p.i++
+			return b.String(), true
+		case '\\':
+			if p.i+1 < len(p.line) {
+				p.i++
+				switch c = p.line[p.i]; c {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				}
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.errorf(start, "string not terminated")
+	return "", false
+}
+
+// stripComment cuts off a "//" comment outside of strings.
+func (p *parser) stripComment() {
+	quoted := false
+	for i := 0; i < len(p.line); i++ {
+		switch c := p.line[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '/' && !quoted && i+1 < len(p.line) && p.line[i+1] == '/':
+			p.line = p.line[:i]
+			return
+		}
+	}
+}
+
+// conditions cuts off the conditions at the end of the line.
+// A condition is a bracketed group at the end of the line
+// which is preceded by white space.
+func (p *parser) conditions() []*Condition {
+	var conds []*Condition
+	for {
+		line := bytes.TrimRight(p.line, " \t")
+		if len(line) == 0 || line[len(line)-1] != ']' {
+			break
+		}
+		depth, open := 0, -1
+	scan:
+		for i := len(line) - 1; i >= 0; i-- {
+			switch line[i] {
+			case ']':
+				depth++
+			case '[':
+				if depth--; depth == 0 {
+					open = i
+					break scan
+				}
+			}
+		}
+		if open < 0 || (open > 0 && !isSpace(line[open-1])) {
+			break
+		}
+		text := strings.TrimSpace(string(line[open+1 : len(line)-1]))
+		cond := &Condition{Pos: p.pos(open + 1), Kind: CodeCond}
+		for k, name := range condNames {
+			if k != int(CodeCond) && strings.EqualFold(text, name) {
+				cond.Kind = CondKind(k)
+				break
+			}
+		}
+		if cond.Kind == CodeCond {
+			if text == "" {
+				p.errorf(open, "empty condition")
+			}
+			cond.Code = text
+		}
+		conds = append(conds, cond)
+		p.line = line[:open]
+	}
+	// Found from right to left.
+	for i, j := 0, len(conds)-1; i < j; i, j = i+1, j-1 {
+		conds[i], conds[j] = conds[j], conds[i]
+	}
+	return conds
+}
+
+func (p *parser) parseLine() {
+	p.stripComment()
+	if p.eol() {
+		return
+	}
+
+	if p.line[p.i] == ':' {
+		p.i++
+		name, start := p.ident()
+		if name == "" {
+			p.errorf(start, "expected label name")
+			return
+		}
+		if !p.eol() {
+			p.errorf(p.i, "unexpected %q after label", p.line[p.i:])
+		}
+		if prev := p.labels[name]; prev != nil {
+			p.errorf(start-1, "label %q already defined at %s", name, prev.Pos)
+		}
+		p.label = &Label{Pos: p.pos(start - 1), Name: name}
+		p.labels[name] = p.label
+		p.dialog.Labels = append(p.dialog.Labels, p.label)
+		return
+	}
+
+	stmt := &Stmt{Pos: p.pos(p.i), Conditions: p.conditions()}
+	if p.eol() {
+		p.errorf(stmt.Pos.Column-1, "condition without statement")
+		return
+	}
+	if stmt.Cmd = p.parseCmd(); stmt.Cmd == nil {
+		return
+	}
+	if !p.eol() {
+		p.errorf(p.i, "unexpected %q", p.line[p.i:])
+		return
+	}
+	if p.label == nil {
+		p.errorf(stmt.Pos.Column-1, "statement outside of a label")
+		return
+	}
+	p.label.Stmts = append(p.label.Stmts, stmt)
+}
+
+// target scans the label name of a goto.
+func (p *parser) target() (string, Pos, bool) {
+	name, start := p.ident()
+	if name == "" {
+		p.errorf(start, "expected label name")
+		return "", Pos{}, false
+	}
+	return name, p.pos(start), true
+}
+
+func (p *parser) arrow() bool {
+	p.skipSpace()
+	if !bytes.HasPrefix(p.line[p.i:], []byte("->")) {
+		p.errorf(p.i, "expected '->'")
+		return false
+	}
+	p.i += 2
+	return true
+}
+
+func (p *parser) yesNo() (bool, bool) {
+	switch w, start := p.word(); strings.ToLower(w) {
+	case "yes", "true", "on":
+		return true, true
+	case "no", "false", "off":
+		return false, true
+	default:
+		p.errorf(start, "expected yes or no, got %q", w)
+		return false, false
+	}
+}
+
+func (p *parser) parseCmd() Cmd {
+	switch c := p.line[p.i]; {
+	case c == '-' && p.i+1 < len(p.line) && p.line[p.i+1] == '>':
+		p.i += 2
+		name, pos, ok := p.target()
+		if !ok {
+			return nil
+		}
+		return &Goto{Label: name, LabelPos: pos}
+
+	case c == '!':
+		p.i++
+		code, start := p.rest()
+		if code == "" {
+			p.errorf(start, "expected code")
+			return nil
+		}
+		return &Code{Code: code}
+
+	case c >= '0' && c <= '9':
+		return p.parseChoice()
+	}
+
+	name, start := p.ident()
+	if name == "" {
+		p.errorf(start, "unexpected %q", p.line[start:])
+		return nil
+	}
+	if p.skipSpace(); p.i < len(p.line) && p.line[p.i] == ':' {
+		p.i++
+		text, ok := p.str()
+		if !ok {
+			return nil
+		}
+		return &Say{Actor: name, Text: text}
+	}
+
+	switch strings.ToLower(name) {
+	case "shutup":
+		return &Shutup{}
+
+	case "pause":
+		w, start := p.word()
+		secs, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			p.errorf(start, "invalid pause %q", w)
+			return nil
+		}
+		return &Pause{Seconds: secs}
+
+	case "waitfor":
+		actor, _ := p.ident()
+		return &WaitFor{Actor: actor}
+
+	case "waitwhile":
+		code, start := p.rest()
+		if code == "" {
+			p.errorf(start, "expected code")
+			return nil
+		}
+		return &WaitWhile{Code: code}
+
+	case "parrot":
+		b, ok := p.yesNo()
+		if !ok {
+			return nil
+		}
+		return &Parrot{Enabled: b}
+
+	case "dialog":
+		actor, start := p.ident()
+		if actor == "" {
+			p.errorf(start, "expected actor")
+			return nil
+		}
+		return &DialogActor{Actor: actor}
+
+	case "override":
+		name, pos, ok := p.target()
+		if !ok {
+			return nil
+		}
+		return &Override{Label: name, LabelPos: pos}
+
+	case "allowobjects":
+		b, ok := p.yesNo()
+		if !ok {
+			return nil
+		}
+		return &AllowObjects{Allowed: b}
+
+	case "limit":
+		w, start := p.word()
+		n, err := strconv.Atoi(w)
+		if err != nil {
+			p.errorf(start, "invalid limit %q", w)
+			return nil
+		}
+		return &Limit{Max: n}
+	}
+
+	p.errorf(start, "unknown command %q", name)
+	return nil
+}
+
+func (p *parser) parseChoice() Cmd {
+	start := p.i
+	for p.i < len(p.line) && p.line[p.i] >= '0' && p.line[p.i] <= '9' {
+		p.i++
+	}
+	n, err := strconv.Atoi(string(p.line[start:p.i]))
+	if err != nil {
+		p.errorf(start, "invalid choice number")
+		return nil
+	}
+	choice := &Choice{Number: n}
+
+	p.skipSpace()
+	if p.i < len(p.line) && p.line[p.i] == '$' {
+		p.i++
+		start := p.i
+		end := bytes.LastIndex(p.line[start:], []byte("->"))
+		if end < 0 {
+			p.errorf(p.i, "expected '->'")
+			return nil
+		}
+		p.i = start + end
+		if choice.Code = string(bytes.TrimSpace(p.line[start:p.i])); choice.Code == "" {
+			p.errorf(start, "expected code")
+			return nil
+		}
+	} else {
+		text, ok := p.str()
+		if !ok {
+			return nil
+		}
+		choice.Text = text
+	}
+
+	if !p.arrow() {
+		return nil
+	}
+	name, pos, ok := p.target()
+	if !ok {
+		return nil
+	}
+	choice.Label, choice.LabelPos = name, pos
+	return choice
+}
@@ -0,0 +1,182 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+package yack
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// dump formats a dialog with one line per label and statement.
+func dump(d *Dialog) string {
+	var lines []string
+	for _, l := range d.Labels {
+		lines = append(lines, l.Pos.String()+" :"+l.Name)
+		for _, s := range l.Stmts {
+			lines = append(lines, s.Pos.String()+" "+s.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func errorStrings(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var errs []string
+	for _, e := range err.(ErrorList) {
+		errs = append(errs, e.Error())
+	}
+	return errs
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+		errs []string
+	}{
+		{
+			name: "lines and gotos",
+			src: ":main\n" +
+				"ray: \"Hello.\"\n" +
+				"-> done\n" +
+				":done\n" +
+				"shutup\n",
+			want: "1:1 :main\n" +
+				"2:1 ray: \"Hello.\"\n" +
+				"3:1 -> done\n" +
+				"4:1 :done\n" +
+				"5:1 shutup",
+		},
+		{
+			name: "conditions",
+			src: ":main\n" +
+				"1 \"Who are you?\" -> who [once]\n" +
+				"2 \"Bye.\" -> exit [!g.met] [showonce]\n" +
+				"ray: \"[not a condition]\"\n" +
+				"reyes: \"Hi.\"[once]\n",
+			want: "1:1 :main\n" +
+				"2:1 1 \"Who are you?\" -> who [once]\n" +
+				"3:1 2 \"Bye.\" -> exit [!g.met] [showonce]\n" +
+				"4:1 ray: \"[not a condition]\"",
+			errs: []string{`5:13: unexpected "[once]"`},
+		},
+		{
+			name: "code choices",
+			src: ":main\n" +
+				"1 $g.names[\"ray\"] -> ray\n" +
+				"2 $a -> b -> c\n" +
+				"3 $ -> d\n",
+			want: "1:1 :main\n" +
+				"2:1 1 $g.names[\"ray\"] -> ray\n" +
+				"3:1 2 $a -> b -> c",
+			errs: []string{"4:4: expected code"},
+		},
+		{
+			name: "comments",
+			src: "// head\n" +
+				":main // label\n" +
+				"ray: \"http://example.com\" // comment\n" +
+				"ray: \"a \\\" // b\"\n" +
+				"!g.x = 1 // set\n",
+			want: "2:1 :main\n" +
+				"3:1 ray: \"http://example.com\"\n" +
+				"4:1 ray: \"a \\\" // b\"\n" +
+				"5:1 !g.x = 1",
+		},
+		{
+			name: "commands",
+			src: ":main\n" +
+				"pause 1.5\n" +
+				"waitfor\n" +
+				"waitfor ray\n" +
+				"waitwhile g.busy\n" +
+				"parrot no\n" +
+				"dialog ray\n" +
+				"override main\n" +
+				"allowobjects yes\n" +
+				"limit 3\n",
+			want: "1:1 :main\n" +
+				"2:1 pause 1.5\n" +
+				"3:1 waitfor\n" +
+				"4:1 waitfor ray\n" +
+				"5:1 waitwhile g.busy\n" +
+				"6:1 parrot no\n" +
+				"7:1 dialog ray\n" +
+				"8:1 override main\n" +
+				"9:1 allowobjects yes\n" +
+				"10:1 limit 3",
+		},
+		{
+			name: "duplicate labels",
+			src: ":main\n" +
+				"-> main\n" +
+				":main\n" +
+				"shutup\n",
+			want: "1:1 :main\n" +
+				"2:1 -> main\n" +
+				"3:1 :main\n" +
+				"4:1 shutup",
+			errs: []string{`3:1: label "main" already defined at 1:1`},
+		},
+		{
+			name: "broken lines",
+			src: "ray: \"early\"\n" +
+				":main\n" +
+				"ray: \"open\n" +
+				"1 \"x\" ->\n" +
+				"ray: \"ok\" [ ]\n" +
+				"  -> main\n" +
+				"  [once]\n" +
+				"[]\n" +
+				"[once] [!g.x]\n",
+			want: "2:1 :main\n" +
+				"5:1 ray: \"ok\" []\n" +
+				"6:3 -> main",
+			errs: []string{
+				"1:1: statement outside of a label",
+				"3:6: string not terminated",
+				"4:9: expected label name",
+				"5:11: empty condition",
+				"7:3: condition without statement",
+				"8:1: empty condition",
+				"8:1: condition without statement",
+				"9:1: condition without statement",
+			},
+		},
+	} {
+		d, err := Parse([]byte(tc.src))
+		if got := dump(d); got != tc.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.name, got, tc.want)
+		}
+		errs := errorStrings(err)
+		if strings.Join(errs, "|") != strings.Join(tc.errs, "|") {
+			t.Errorf("%s: got errors %q, want %q", tc.name, errs, tc.errs)
+		}
+	}
+}
+
+func TestStmtJSON(t *testing.T) {
+	d, err := Parse([]byte(":main\n1 \"Hi\" -> main [once]\nshutup\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(d.Labels[0].Stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"pos":"2:1","cmd":"choice","number":1,"text":"Hi","label":"main",` +
+		`"labelPos":"2:11","conditions":[{"pos":"2:17","kind":"once"}]},` +
+		`{"pos":"3:1","cmd":"shutup"}]`
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,347 @@
+// This is Free Software under the terms of the MIT License.
+//
+// SPDX-License-Identifier: MIT
+// icense-Filename: LICENSE
+//
+// Copyright (c) 2020 by Sascha L. Teichmann
+
+// Package yack parses the dialog scripts (.yack) of the game.
+//
+// A dialog is a list of labels. Every label starts with a line
+// ":name" and is followed by statements, one per line:
+//
+//	ray: "Hello."            actor line
+//	1 "Who are you?" -> who  choice
+//	2 $g.name -> name        choice with computed text
+//	-> main                  goto
+//	!g.met = YES             Squirrel code
+//	shutup, pause 1.5, waitfor ray, waitwhile g.busy,
+//	parrot no, dialog ray, override main, allowobjects yes, limit 3
+//
+// Statements may be followed by conditions in brackets:
+// [once], [showonce], [onceever], [showonceever], [temponce]
+// or a Squirrel expression like [!g.met].
+// Comments start with "//".
+//
+// .byack files are parsed like .yack files. No encoding of
+// compiled dialogs is known, so binary .byack content is not
+// decoded.
+package yack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pos is a position in the source.
+// Lines and columns start with 1, columns count bytes.
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Pos) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// Error is a syntax error.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorList is a list of syntax errors in the order they were found.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+	}
+}
+
+// Err returns the list as error or nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Dialog is a parsed yack script.
+type Dialog struct {
+	Labels []*Label `json:"labels"`
+}
+
+// Label is a named sequence of statements.
+type Label struct {
+	Pos   Pos     `json:"pos"`
+	Name  string  `json:"name"`
+	Stmts []*Stmt `json:"stmts"`
+}
+
+// Label returns the label with the given name or nil.
+func (d *Dialog) Label(name string) *Label {
+	for _, l := range d.Labels {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Stmt is a statement with its conditions.
+type Stmt struct {
+	Pos        Pos
+	Cmd        Cmd
+	Conditions []*Condition
+}
+
+// Cmd is the command of a statement. It is one of
+// *Say, *Choice, *Goto, *Code, *Shutup, *Pause, *WaitFor,
+// *WaitWhile, *Parrot, *DialogActor, *Override, *AllowObjects
+// and *Limit.
+type Cmd interface {
+	// Name returns the name of the command like "say" or "choice".
+	Name() string
+	// String returns the command in yack syntax.
+	String() string
+}
+
+type (
+	// Say is an actor line: actor: "text"
+	Say struct {
+		Actor string `json:"actor"`
+		Text  string `json:"text"`
+	}
+
+	// Choice is an entry of the choice menu: 1 "text" -> label
+	// If the text is given as $expr Code holds the expression
+	// and Text is empty.
+	Choice struct {
+		Number   int    `json:"number"`
+		Text     string `json:"text,omitempty"`
+		Code     string `json:"code,omitempty"`
+		Label    string `json:"label"`
+		LabelPos Pos    `json:"labelPos"`
+	}
+
+	// Goto continues the dialog at a label: -> label
+	Goto struct {
+		Label    string `json:"label"`
+		LabelPos Pos    `json:"labelPos"`
+	}
+
+	// Code is a line of Squirrel code: !code
+	Code struct {
+		Code string `json:"code"`
+	}
+
+	// Shutup stops all talking actors.
+	Shutup struct{}
+
+	// Pause waits for the given time.
+	Pause struct {
+		Seconds float64 `json:"seconds"`
+	}
+
+	// WaitFor waits until an actor stops talking.
+	// Without an actor it waits for all actors.
+	WaitFor struct {
+		Actor string `json:"actor,omitempty"`
+	}
+
+	// WaitWhile waits while the Squirrel condition is true.
+	WaitWhile struct {
+		Code string `json:"code"`
+	}
+
+	// Parrot toggles if the chosen choice is said by the actor.
+	Parrot struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	// DialogActor sets the actor which says the chosen choices.
+	DialogActor struct {
+		Actor string `json:"actor"`
+	}
+
+	// Override sets the label to continue at if the dialog is skipped.
+	Override struct {
+		Label    string `json:"label"`
+		LabelPos Pos    `json:"labelPos"`
+	}
+
+	// AllowObjects toggles if objects can be used during the dialog.
+	AllowObjects struct {
+		Allowed bool `json:"allowed"`
+	}
+
+	// Limit limits the number of choices shown at once.
+	Limit struct {
+		Max int `json:"max"`
+	}
+)
+
+func (*Say) Name() string          { return "say" }
+func (*Choice) Name() string       { return "choice" }
+func (*Goto) Name() string         { return "goto" }
+func (*Code) Name() string         { return "code" }
+func (*Shutup) Name() string       { return "shutup" }
+func (*Pause) Name() string        { return "pause" }
+func (*WaitFor) Name() string      { return "waitfor" }
+func (*WaitWhile) Name() string    { return "waitwhile" }
+func (*Parrot) Name() string       { return "parrot" }
+func (*DialogActor) Name() string  { return "dialog" }
+func (*Override) Name() string     { return "override" }
+func (*AllowObjects) Name() string { return "allowobjects" }
+func (*Limit) Name() string        { return "limit" }
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func (c *Say) String() string { return c.Actor + ": " + strconv.Quote(c.Text) }
+
+func (c *Choice) String() string {
+	text := strconv.Quote(c.Text)
+	if c.Code != "" {
+		text = "$" + c.Code
+	}
+	return fmt.Sprintf("%d %s -> %s", c.Number, text, c.Label)
+}
+
+func (c *Goto) String() string      { return "-> " + c.Label }
+func (c *Code) String() string      { return "!" + c.Code }
+func (c *Shutup) String() string    { return "shutup" }
+func (c *Pause) String() string     { return "pause " + strconv.FormatFloat(c.Seconds, 'g', -1, 64) }
+func (c *WaitWhile) String() string { return "waitwhile " + c.Code }
+
+func (c *WaitFor) String() string {
+	if c.Actor == "" {
+		return "waitfor"
+	}
+	return "waitfor " + c.Actor
+}
+
+func (c *Parrot) String() string       { return "parrot " + yesNo(c.Enabled) }
+func (c *DialogActor) String() string  { return "dialog " + c.Actor }
+func (c *Override) String() string     { return "override " + c.Label }
+func (c *AllowObjects) String() string { return "allowobjects " + yesNo(c.Allowed) }
+func (c *Limit) String() string        { return "limit " + strconv.Itoa(c.Max) }
+
+// String returns the statement in yack syntax.
+func (s *Stmt) String() string {
+	var b strings.Builder
+	b.WriteString(s.Cmd.String())
+	for _, c := range s.Conditions {
+		b.WriteString(" [")
+		b.WriteString(c.String())
+		b.WriteByte(']')
+	}
+	return b.String()
+}
+
+// MarshalJSON implements json.Marshaler. The fields of the
+// command are written next to "pos" and "cmd".
+func (s *Stmt) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteString(`{"pos":"` + s.Pos.String() + `","cmd":"` + s.Cmd.Name() + `"`)
+	mark := buf.Len()
+	if err := enc.Encode(s.Cmd); err != nil {
+		return nil, err
+	}
+	// Splice the fields of the command in.
+	fields := bytes.TrimSpace(buf.Bytes()[mark:])
+	fields = fields[1 : len(fields)-1]
+	if len(fields) > 0 {
+		fields = append([]byte{','}, fields...)
+	}
+	buf.Truncate(mark)
+	buf.Write(fields)
+	if len(s.Conditions) > 0 {
+		buf.WriteString(`,"conditions":`)
+		if err := enc.Encode(s.Conditions); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// CondKind is the kind of a condition.
+type CondKind int
+
+const (
+	// CodeCond is a Squirrel expression.
+	CodeCond CondKind = iota
+	// Once is true until the statement was executed.
+	Once
+	// ShowOnce is true until the choice was shown.
+	ShowOnce
+	// OnceEver is like Once but kept over all dialogs.
+	OnceEver
+	// ShowOnceEver is like ShowOnce but kept over all dialogs.
+	ShowOnceEver
+	// TempOnce is like Once but reset when the dialog is restarted.
+	TempOnce
+)
+
+var condNames = [...]string{
+	CodeCond:     "code",
+	Once:         "once",
+	ShowOnce:     "showonce",
+	OnceEver:     "onceever",
+	ShowOnceEver: "showonceever",
+	TempOnce:     "temponce",
+}
+
+func (k CondKind) String() string {
+	if k >= 0 && int(k) < len(condNames) {
+		return condNames[k]
+	}
+	return fmt.Sprintf("CondKind(%d)", int(k))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k CondKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Condition guards a statement.
+type Condition struct {
+	Pos  Pos      `json:"pos"`
+	Kind CondKind `json:"kind"`
+	// Code is the Squirrel expression of a CodeCond.
+	Code string `json:"code,omitempty"`
+}
+
+func (c *Condition) String() string {
+	if c.Kind == CodeCond {
+		return c.Code
+	}
+	return c.Kind.String()
+}